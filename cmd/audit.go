@@ -0,0 +1,75 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/bank-vaults/secret-sync/pkg/audit"
+	"github.com/bank-vaults/secret-sync/pkg/storesync"
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Inspects audit logs written by sync jobs.",
+}
+
+var auditVerifyCmd = &cobra.Command{
+	Use:   "verify <audit-log>",
+	Short: "Verifies that an audit log was not modified.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runAuditVerify,
+}
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+	auditCmd.AddCommand(auditVerifyCmd)
+}
+
+func runAuditVerify(cmd *cobra.Command, args []string) error {
+	cmd.SilenceUsage = true
+
+	file, err := os.Open(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer file.Close()
+
+	count, err := audit.Verify(file)
+	if err != nil {
+		return fmt.Errorf("failed to verify audit log: %w", err)
+	}
+
+	_, err = fmt.Fprintf(cmd.OutOrStdout(), "Verified %d audit records\n", count)
+	return err
+}
+
+// writeAudit appends records of all sync operations to the audit log of the job.
+func writeAudit(job *syncJob, status *storesync.Status) error {
+	log, err := audit.Open(job.syncPlan.AuditLogPath)
+	if err != nil {
+		return err
+	}
+
+	if err := log.Write(audit.NewRecords(syncCmdParams.SyncJobPath, status)...); err != nil {
+		_ = log.Close()
+		return err
+	}
+
+	return log.Close()
+}
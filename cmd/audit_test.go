@@ -0,0 +1,103 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAudit(t *testing.T) {
+	dir := t.TempDir()
+	auditPath := filepath.Join(dir, "audit.log")
+	syncPath := filepath.Join(dir, "syncjob.yaml")
+	require.NoError(t, os.WriteFile(syncPath, []byte(fmt.Sprintf(`
+auditLogPath: %q
+sync:
+  - secretRef:
+      key: /source/credentials/username
+  - secretRef:
+      key: /source/credentials/missing
+`, auditPath)), 0o600))
+
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	t.Cleanup(func() {
+		rootCmd.SetOut(nil)
+		rootCmd.SetArgs(nil)
+	})
+	rootCmd.SetArgs([]string{
+		"sync",
+		"--source", localStore(t, "testdata"),
+		"--target", localStore(t, filepath.Join(dir, "target")),
+		"--syncjob", syncPath,
+	})
+	require.NoError(t, rootCmd.ExecuteContext(context.Background()))
+
+	// Records are written for every operation, without values
+	data, err := os.ReadFile(auditPath)
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(data), "\n"))
+	assert.Contains(t, string(data), `"outcome":"synced"`)
+	assert.Contains(t, string(data), `"outcome":"failed"`)
+	assert.Contains(t, string(data), `"job":"`+syncPath+`"`)
+
+	value, err := os.ReadFile("testdata/source/credentials/username")
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), string(value))
+
+	// Unmodified log is valid
+	rootCmd.SetArgs([]string{"audit", "verify", auditPath})
+	require.NoError(t, rootCmd.ExecuteContext(context.Background()))
+	assert.Contains(t, out.String(), "Verified 2 audit records")
+
+	// Modified log is detected
+	tampered := strings.Replace(string(data), `"outcome":"failed"`, `"outcome":"synced"`, 1)
+	require.NoError(t, os.WriteFile(auditPath, []byte(tampered), 0o600))
+	rootCmd.SetArgs([]string{"audit", "verify", auditPath})
+	assert.Error(t, rootCmd.ExecuteContext(context.Background()))
+}
+
+func TestAuditFailure(t *testing.T) {
+	dir := t.TempDir()
+	syncPath := filepath.Join(dir, "syncjob.yaml")
+	require.NoError(t, os.WriteFile(syncPath, []byte(fmt.Sprintf(`
+auditLogPath: %q
+sync:
+  - secretRef:
+      key: /source/credentials/username
+`, filepath.Join(syncPath, "audit.log"))), 0o600))
+
+	t.Cleanup(func() { rootCmd.SetArgs(nil) })
+	rootCmd.SetArgs([]string{
+		"sync",
+		"--source", localStore(t, "testdata"),
+		"--target", localStore(t, filepath.Join(dir, "target")),
+		"--syncjob", syncPath,
+	})
+
+	// Sync fails if its operations cannot be audited
+	err := rootCmd.ExecuteContext(context.Background())
+	require.ErrorIs(t, err, errAuditFailed)
+	assert.Equal(t, exitCodeFailed, exitCode(err))
+}
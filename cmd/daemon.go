@@ -0,0 +1,223 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/spf13/cobra"
+
+	"github.com/bank-vaults/secret-sync/pkg/storesync"
+)
+
+const (
+	flagInterval      = "interval"
+	flagSchedule      = "schedule"
+	flagJitter        = "jitter"
+	flagStatusAddress = "status-address"
+
+	// statusShutdownTimeout limits how long the status server can take to shut down.
+	statusShutdownTimeout = 5 * time.Second
+)
+
+var daemonCmdParams = struct {
+	Interval      time.Duration
+	Schedule      string
+	Jitter        time.Duration
+	StatusAddress string
+}{}
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Periodically synchronizes secrets from a source to a target store based on sync strategy.",
+	RunE:  runDaemon,
+}
+
+func init() {
+	rootCmd.AddCommand(daemonCmd)
+	daemonCmd.PersistentFlags().StringVarP(&syncCmdParams.SourceStorePath, flagSource, "s", "", "Source store config file.")
+	_ = daemonCmd.MarkPersistentFlagRequired(flagSource)
+	daemonCmd.PersistentFlags().StringVarP(&syncCmdParams.TargetStorePath, flagTarget, "t", "", "Target store config file. Required unless the sync job defines targets.")
+	daemonCmd.PersistentFlags().StringVar(&syncCmdParams.SyncJobPath, flagSyncJob, "", "Sync job config file. ")
+	_ = daemonCmd.MarkPersistentFlagRequired(flagSyncJob)
+	daemonCmd.Flags().DurationVar(&daemonCmdParams.Interval, flagInterval, 0, "Time between syncs, e.g. 5m.")
+	daemonCmd.Flags().StringVar(&daemonCmdParams.Schedule, flagSchedule, "", "Cron expression defining when to sync, e.g. \"*/5 * * * *\".")
+	daemonCmd.MarkFlagsOneRequired(flagInterval, flagSchedule)
+	daemonCmd.MarkFlagsMutuallyExclusive(flagInterval, flagSchedule)
+	daemonCmd.Flags().DurationVar(&daemonCmdParams.Jitter, flagJitter, 10*time.Second, "Maximum random delay added to each scheduled sync.")
+	daemonCmd.Flags().DurationVar(&syncCmdParams.Timeout, flagTimeout, 0, "Maximum duration of each sync, e.g. 10m. Unlimited if zero.")
+	daemonCmd.Flags().StringVar(&daemonCmdParams.StatusAddress, flagStatusAddress, "", "Address to serve the last sync status on, e.g. :8080. Disabled if empty.")
+}
+
+// daemon runs a sync job on schedule, reusing store clients between runs.
+type daemon struct {
+	job      *syncJob
+	schedule cron.Schedule
+	jitter   time.Duration
+
+	mu     sync.RWMutex
+	status *storesync.Status // nil until the first sync completes
+}
+
+func runDaemon(cmd *cobra.Command, args []string) error {
+	schedule, err := parseSchedule(daemonCmdParams.Interval, daemonCmdParams.Schedule)
+	if err != nil {
+		return err
+	}
+	if daemonCmdParams.Jitter < 0 {
+		return fmt.Errorf("invalid --%s, must not be negative", flagJitter)
+	}
+	cmd.SilenceUsage = true
+	ctx := cmd.Root().Context()
+
+	syncJob, err := prepareSync(cmd, args)
+	if err != nil {
+		return fmt.Errorf("failed to prepare sync job: %w", err)
+	}
+	defer syncJob.close(cmd)
+
+	d := &daemon{
+		job:      syncJob,
+		schedule: schedule,
+		jitter:   daemonCmdParams.Jitter,
+	}
+
+	if daemonCmdParams.StatusAddress != "" {
+		shutdown, err := d.serveStatus(ctx, daemonCmdParams.StatusAddress)
+		if err != nil {
+			return err
+		}
+		defer shutdown()
+	}
+
+	d.run(ctx)
+	return nil
+}
+
+// parseSchedule returns a schedule for either an interval or a cron expression.
+func parseSchedule(interval time.Duration, expression string) (cron.Schedule, error) {
+	if expression != "" {
+		schedule, err := cron.ParseStandard(expression)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --%s: %w", flagSchedule, err)
+		}
+
+		return schedule, nil
+	}
+
+	if interval < time.Second {
+		return nil, fmt.Errorf("invalid --%s, must be at least 1s", flagInterval)
+	}
+
+	return cron.Every(interval), nil
+}
+
+// run syncs immediately and then on schedule until ctx is done.
+// A sync in progress stops scheduling writes, but lets in-flight writes finish within the grace period.
+func (d *daemon) run(ctx context.Context) {
+	for {
+		status, err := d.job.sync(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, err.Error())
+		}
+		if status != nil {
+			d.mu.Lock()
+			d.status = status
+			d.mu.Unlock()
+		}
+
+		next := d.next(time.Now())
+		slog.InfoContext(ctx, "Scheduled next sync", slog.Any("at", next))
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			slog.InfoContext(ctx, "Stopped scheduling syncs")
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// next returns when to run the next sync after now.
+func (d *daemon) next(now time.Time) time.Time {
+	next := d.schedule.Next(now)
+	if d.jitter > 0 {
+		next = next.Add(rand.N(d.jitter + 1))
+	}
+
+	return next
+}
+
+// lastStatus returns the status of the last completed sync.
+func (d *daemon) lastStatus() *storesync.Status {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.status
+}
+
+// ServeHTTP responds with the status of the last completed sync as JSON.
+func (d *daemon) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	status := d.lastStatus()
+	if status == nil {
+		http.Error(w, "no sync completed yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(status)
+}
+
+// serveStatus serves the last sync status in the background.
+// Returned func shuts the server down.
+func (d *daemon) serveStatus(ctx context.Context, address string) (func(), error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on status address: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /status", d)
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.ErrorContext(ctx, fmt.Errorf("failed to serve status: %w", err).Error())
+		}
+	}()
+	slog.InfoContext(ctx, "Serving sync status", slog.Any("address", listener.Addr().String()))
+
+	return func() {
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), statusShutdownTimeout)
+		defer cancel()
+
+		_ = server.Shutdown(shutdownCtx)
+	}, nil
+}
@@ -0,0 +1,147 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bank-vaults/secret-sync/pkg/storesync"
+)
+
+func TestParseSchedule(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 2, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		interval   time.Duration
+		expression string
+		wantNext   time.Time
+		wantErr    bool
+	}{
+		{
+			name:     "Interval",
+			interval: time.Minute,
+			wantNext: now.Add(time.Minute),
+		},
+		{
+			name:       "Cron expression",
+			expression: "*/5 * * * *",
+			wantNext:   time.Date(2024, 1, 1, 10, 5, 0, 0, time.UTC),
+		},
+		{
+			name:     "Interval too short",
+			interval: time.Millisecond,
+			wantErr:  true,
+		},
+		{
+			name:       "Invalid cron expression",
+			expression: "every minute",
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		ttp := tt
+		t.Run(ttp.name, func(t *testing.T) {
+			schedule, err := parseSchedule(ttp.interval, ttp.expression)
+			if ttp.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, ttp.wantNext, schedule.Next(now))
+		})
+	}
+}
+
+func TestDaemon(t *testing.T) {
+	dir := t.TempDir()
+	syncPath := filepath.Join(dir, "syncjob.yaml")
+	require.NoError(t, os.WriteFile(syncPath, []byte("sync:\n  - secretRef:\n      key: /source/credentials/username\n"), 0o600))
+	targetPath := filepath.Join(dir, "target")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(func() {
+		cancel()
+		rootCmd.SetArgs(nil)
+		daemonCmdParams.Interval = 0
+		daemonCmdParams.Jitter = 0
+	})
+	rootCmd.SetArgs([]string{
+		"daemon",
+		"--source", localStore(t, "testdata"),
+		"--target", localStore(t, targetPath),
+		"--syncjob", syncPath,
+		"--interval", "1s",
+		"--jitter", "0s",
+	})
+
+	done := make(chan error)
+	go func() {
+		done <- rootCmd.ExecuteContext(ctx)
+	}()
+
+	// Secrets are synced again after being removed from target
+	syncedPath := filepath.Join(targetPath, "source", "credentials", "username")
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(syncedPath)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	require.NoError(t, os.Remove(syncedPath))
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(syncedPath)
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// Stops on shutdown
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("daemon did not stop")
+	}
+}
+
+func TestDaemonStatus(t *testing.T) {
+	d := &daemon{}
+
+	// No sync completed yet
+	recorder := httptest.NewRecorder()
+	d.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/status", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+
+	// Last sync status
+	d.status = &storesync.Status{Total: 1, Synced: 1, Success: true}
+	recorder = httptest.NewRecorder()
+	d.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/status", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var status storesync.Status
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &status))
+	assert.True(t, status.Success)
+	assert.Equal(t, uint32(1), status.Synced)
+}
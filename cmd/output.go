@@ -0,0 +1,162 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ghodss/yaml"
+
+	"github.com/bank-vaults/secret-sync/pkg/storesync"
+)
+
+// Supported sync report formats.
+const (
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+	outputJUnit = "junit"
+)
+
+func validateOutput(format string) error {
+	switch format {
+	case "", outputJSON, outputYAML, outputJUnit:
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format %q, expected one of: json, yaml, junit", format)
+	}
+}
+
+// writeStatus writes sync status report in the given format.
+func writeStatus(out io.Writer, format string, status *storesync.Status) error {
+	var data []byte
+	var err error
+	switch format {
+	case outputJSON:
+		data, err = json.MarshalIndent(status, "", "  ")
+		data = append(data, '\n')
+	case outputYAML:
+		data, err = yaml.Marshal(status)
+	case outputJUnit:
+		data, err = xml.MarshalIndent(newJUnitReport(status), "", "  ")
+		data = append([]byte(xml.Header), append(data, '\n')...)
+	default:
+		return validateOutput(format)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to marshal sync report: %w", err)
+	}
+
+	if _, err := out.Write(data); err != nil {
+		return fmt.Errorf("failed to write sync report: %w", err)
+	}
+
+	return nil
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+}
+
+// newJUnitReport returns a report with a test case for each key and each action which could not be fetched.
+// Syncs to multiple targets are reported as a test suite per target.
+func newJUnitReport(status *storesync.Status) junitTestSuites {
+	if len(status.Targets) <= 1 {
+		return junitTestSuites{Suites: []junitTestSuite{newJUnitSuite("secret-sync", status)}}
+	}
+
+	var report junitTestSuites
+	for _, target := range status.Targets {
+		report.Suites = append(report.Suites, newJUnitSuite("secret-sync/"+target.Name, &target.Status))
+	}
+
+	return report
+}
+
+func newJUnitSuite(name string, status *storesync.Status) junitTestSuite {
+	suite := junitTestSuite{
+		Name:      name,
+		Timestamp: status.SyncedAt.Format(time.RFC3339),
+	}
+
+	var total time.Duration
+	for _, id := range status.Failed {
+		suite.TestCases = append(suite.TestCases, junitTestCase{
+			Name:      fmt.Sprintf("action %d", id),
+			ClassName: junitClassName(id),
+			Time:      junitTime(0),
+			Failure:   &junitMessage{Message: "failed to fetch sync action"},
+		})
+		suite.Failures++
+	}
+
+	for _, result := range status.Results {
+		testCase := junitTestCase{
+			Name:      result.Key,
+			ClassName: junitClassName(result.RequestID),
+			Time:      junitTime(time.Duration(result.DurationMs) * time.Millisecond),
+		}
+		switch {
+		case result.Outcome == storesync.OutcomeSkipped:
+			testCase.Skipped = &junitMessage{Message: result.Error}
+			suite.Skipped++
+		case result.Failed():
+			testCase.Failure = &junitMessage{Message: fmt.Sprintf("%s: %s", result.Outcome, result.Error)}
+			suite.Failures++
+		}
+
+		suite.TestCases = append(suite.TestCases, testCase)
+		total += time.Duration(result.DurationMs) * time.Millisecond
+	}
+
+	suite.Tests = len(suite.TestCases)
+	suite.Time = junitTime(total)
+
+	return suite
+}
+
+func junitClassName(id int) string {
+	return fmt.Sprintf("sync.action-%d", id)
+}
+
+func junitTime(duration time.Duration) string {
+	return fmt.Sprintf("%.3f", duration.Seconds())
+}
@@ -0,0 +1,111 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/bank-vaults/secret-sync/pkg/storesync"
+)
+
+var planCmd = &cobra.Command{
+	Use:   "plan",
+	Short: "Shows changes a sync would make to the target store without applying them.",
+	RunE:  runPlan,
+}
+
+func init() {
+	rootCmd.AddCommand(planCmd)
+	planCmd.PersistentFlags().StringVarP(&syncCmdParams.SourceStorePath, flagSource, "s", "", "Source store config file.")
+	_ = planCmd.MarkPersistentFlagRequired(flagSource)
+	planCmd.PersistentFlags().StringVarP(&syncCmdParams.TargetStorePath, flagTarget, "t", "", "Target store config file. Required unless the sync job defines targets.")
+	planCmd.PersistentFlags().StringVar(&syncCmdParams.SyncJobPath, flagSyncJob, "", "Sync job config file. ")
+	_ = planCmd.MarkPersistentFlagRequired(flagSyncJob)
+}
+
+func runPlan(cmd *cobra.Command, args []string) error {
+	syncJob, err := prepareSync(cmd, args)
+	if err != nil {
+		return fmt.Errorf("failed to prepare sync job: %w", err)
+	}
+	defer syncJob.close(cmd)
+
+	plan, err := storesync.PlanTargets(cmd.Root().Context(), *syncJob.source, syncJob.targets, syncJob.syncPlan.SyncAction, syncJob.options()...)
+	if err != nil {
+		return fmt.Errorf("failed to plan secrets: %w", err)
+	}
+
+	if syncJob.singleTarget() {
+		return printPlan(cmd.OutOrStdout(), &plan.Targets[0].PlanResult)
+	}
+
+	for _, target := range plan.Targets {
+		if _, err := fmt.Fprintf(cmd.OutOrStdout(), "Target %s:\n\n", target.Name); err != nil {
+			return fmt.Errorf("failed to write plan: %w", err)
+		}
+		if err := printPlan(cmd.OutOrStdout(), &target.PlanResult); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(cmd.OutOrStdout()); err != nil {
+			return fmt.Errorf("failed to write plan: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// printPlan writes a plan as a table. Values are only shown as keyed hashes and lengths.
+func printPlan(out io.Writer, plan *storesync.PlanResult) error {
+	table := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(table, "ACTION\tKEY\tCURRENT\tDESIRED")
+	for _, key := range plan.Keys {
+		desired := formatValue(key.Desired)
+		if key.Error != "" {
+			desired = "error: " + key.Error
+		}
+		_, _ = fmt.Fprintf(table, "%s\t%s\t%s\t%s\n", key.Action, key.Key, formatValue(key.Current), desired)
+	}
+	if err := table.Flush(); err != nil {
+		return fmt.Errorf("failed to write plan: %w", err)
+	}
+
+	if len(plan.Collisions) > 0 {
+		_, _ = fmt.Fprintln(out, "\nCollisions:")
+		for _, collision := range plan.Collisions {
+			winner := "values merged"
+			if collision.Winner >= 0 {
+				winner = fmt.Sprintf("action %d wins", collision.Winner)
+			}
+			_, _ = fmt.Fprintf(out, "  %s: actions %v resolved by %s, %s\n", collision.Key, collision.Actions, collision.Policy, winner)
+		}
+	}
+
+	_, err := fmt.Fprintf(out, "\nPlan: %d to create, %d to update, %d unchanged, %d to delete, %d unknown\n",
+		plan.Create, plan.Update, plan.Unchanged, plan.Delete, plan.Unknown)
+
+	return err
+}
+
+func formatValue(value *storesync.ValueSummary) string {
+	if value == nil {
+		return "-"
+	}
+
+	return fmt.Sprintf("%s (%d bytes)", value.Hash, value.Length)
+}
@@ -16,11 +16,14 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net"
 	"os"
+	"os/signal"
 	"slices"
+	"syscall"
 
 	slogmulti "github.com/samber/slog-multi"
 	slogsyslog "github.com/samber/slog-syslog"
@@ -39,11 +42,51 @@ to interact and orchestrate the synchronization of secrets between them.`,
 	},
 }
 
+// Exit codes returned by the CLI.
+const (
+	exitCodeFailed         = 1 // command could not run or failed, e.g. due to invalid config
+	exitCodePartialFailure = 2 // sync completed, but some secrets were not synced with failAtEnd policy
+	exitCodeAborted        = 3 // sync was stopped on the first failure or cancelled
+)
+
+// exitError defines an error which exits the CLI with a specific code.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
 func Execute() {
-	if err := rootCmd.ExecuteContext(context.Background()); err != nil {
+	// Cancel commands on shutdown, a second signal terminates immediately
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	err := rootCmd.ExecuteContext(ctx)
+	stop()
+	if err != nil {
 		slog.ErrorContext(rootCmd.Context(), fmt.Sprintf("failed to execute command: %v", err))
-		os.Exit(1)
+		os.Exit(exitCode(err))
+	}
+}
+
+// exitCode returns the code the CLI should exit with for an error.
+func exitCode(err error) int {
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
 	}
+
+	return exitCodeFailed
 }
 
 func init() {
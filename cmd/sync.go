@@ -15,10 +15,14 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/ghodss/yaml"
 	"github.com/spf13/cobra"
@@ -29,20 +33,29 @@ import (
 )
 
 const (
-	flagSource  = "source"
-	flagTarget  = "target"
-	flagSyncJob = "syncjob"
+	flagSource     = "source"
+	flagTarget     = "target"
+	flagSyncJob    = "syncjob"
+	flagOutput     = "output"
+	flagOutputFile = "output-file"
+	flagTimeout    = "timeout"
 )
 
+// errAuditFailed is returned by syncJob.sync when sync operations could not be recorded in the audit log.
+var errAuditFailed = errors.New("failed to write audit log")
+
 var syncCmdParams = struct {
 	SourceStorePath string
 	TargetStorePath string
 	SyncJobPath     string
+	Output          string
+	OutputFile      string
+	Timeout         time.Duration
 }{}
 
 type syncJob struct {
 	source   *v1alpha1.StoreClient
-	target   *v1alpha1.StoreClient
+	targets  []storesync.Target // single unnamed target if given via --target
 	syncPlan *v1alpha1.SyncPlan
 }
 
@@ -56,28 +69,64 @@ func init() {
 	rootCmd.AddCommand(syncCmd)
 	syncCmd.PersistentFlags().StringVarP(&syncCmdParams.SourceStorePath, flagSource, "s", "", "Source store config file.")
 	_ = syncCmd.MarkPersistentFlagRequired(flagSource)
-	syncCmd.PersistentFlags().StringVarP(&syncCmdParams.TargetStorePath, flagTarget, "t", "", "Target store config file. ")
-	_ = syncCmd.MarkPersistentFlagRequired(flagTarget)
+	syncCmd.PersistentFlags().StringVarP(&syncCmdParams.TargetStorePath, flagTarget, "t", "", "Target store config file. Required unless the sync job defines targets.")
 	syncCmd.PersistentFlags().StringVar(&syncCmdParams.SyncJobPath, flagSyncJob, "", "Sync job config file. ")
 	_ = syncCmd.MarkPersistentFlagRequired(flagSyncJob)
+	syncCmd.Flags().StringVarP(&syncCmdParams.Output, flagOutput, "o", "", "Sync report output format, one of: json, yaml, junit.")
+	syncCmd.Flags().StringVar(&syncCmdParams.OutputFile, flagOutputFile, "", "Sync report output file, requires --output. Defaults to stdout.")
+	syncCmd.Flags().DurationVar(&syncCmdParams.Timeout, flagTimeout, 0, "Maximum duration of the sync, e.g. 10m. Unlimited if zero.")
 }
 
 func run(cmd *cobra.Command, args []string) error {
+	if err := validateOutput(syncCmdParams.Output); err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+
 	syncJob, err := prepareSync(cmd, args)
 	if err != nil {
 		return fmt.Errorf("failed to prepare sync job: %w", err)
 	}
+	defer syncJob.close(cmd)
 
-	resp, err := storesync.Sync(cmd.Root().Context(), *syncJob.source, *syncJob.target, syncJob.syncPlan.SyncAction)
-	if err != nil {
-		return fmt.Errorf("failed to sync secrets: %w", err)
+	resp, err := syncJob.sync(cmd.Root().Context())
+	if resp != nil {
+		if err := writeReport(cmd, resp); err != nil {
+			return err
+		}
+	}
+
+	return syncExitError(err)
+}
+
+// syncExitError returns the error to exit the sync command with, depending on why the sync failed.
+func syncExitError(err error) error {
+	if errors.Is(err, errAuditFailed) {
+		return err
+	}
+	if errors.Is(err, storesync.ErrSyncAborted) {
+		return &exitError{code: exitCodeAborted, err: err}
+	}
+	if errors.Is(err, storesync.ErrSyncFailed) {
+		return &exitError{code: exitCodePartialFailure, err: err}
 	}
-	slog.InfoContext(cmd.Root().Context(), resp.Status)
 
-	return nil
+	// With continue policy, failures are only reported in sync status
+	return err
 }
 
 func prepareSync(cmd *cobra.Command, _ []string) (*syncJob, error) {
+	// Init sync request by loading from file and overriding from cli
+	syncPlan, err := loadSyncPlan(syncCmdParams.SyncJobPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sync plan: %w", err)
+	}
+
+	targetStores, err := loadTargetStores(syncPlan)
+	if err != nil {
+		return nil, err
+	}
+
 	// Init source
 	sourceStore, err := loadStore(syncCmdParams.SourceStorePath)
 	if err != nil {
@@ -89,28 +138,171 @@ func prepareSync(cmd *cobra.Command, _ []string) (*syncJob, error) {
 		return nil, fmt.Errorf("failed to create source client: %w", err)
 	}
 
-	// Init target
-	targetStore, err := loadStore(syncCmdParams.TargetStorePath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to load target store: %w", err)
+	job := &syncJob{
+		source:   &sourceProvider,
+		syncPlan: syncPlan,
+	}
+
+	// Init targets
+	for _, target := range targetStores {
+		targetProvider, err := provider.NewClient(cmd.Root().Context(), target.store)
+		if err != nil {
+			job.close(cmd)
+			if target.Name != "" {
+				return nil, fmt.Errorf("failed to create target %q client: %w", target.Name, err)
+			}
+			return nil, fmt.Errorf("failed to create target client: %w", err)
+		}
+
+		job.targets = append(job.targets, storesync.Target{
+			Name:    target.Name,
+			Store:   targetProvider,
+			Actions: target.Actions,
+		})
+	}
+
+	return job, nil
+}
+
+// targetStore defines a loaded target store config.
+type targetStore struct {
+	v1alpha1.SyncPlanTarget
+	store *v1alpha1.SecretStoreSpec
+}
+
+// loadTargetStores loads the target store given via --target, or all targets defined by the sync plan.
+func loadTargetStores(syncPlan *v1alpha1.SyncPlan) ([]targetStore, error) {
+	if syncCmdParams.TargetStorePath != "" {
+		if len(syncPlan.Targets) > 0 {
+			return nil, fmt.Errorf("targets are defined by both --%s and the sync plan", flagTarget)
+		}
+
+		store, err := loadStore(syncCmdParams.TargetStorePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load target store: %w", err)
+		}
+
+		return []targetStore{{store: store}}, nil
+	}
+
+	if len(syncPlan.Targets) == 0 {
+		return nil, fmt.Errorf("no target store, requires --%s or targets in the sync plan", flagTarget)
+	}
+
+	var targets []targetStore
+	for i, target := range syncPlan.Targets {
+		if target.Name == "" || target.StorePath == "" {
+			return nil, fmt.Errorf("sync plan target %d requires 'name' and 'storePath'", i)
+		}
+
+		storePath := target.StorePath
+		if !filepath.IsAbs(storePath) {
+			storePath = filepath.Join(filepath.Dir(syncCmdParams.SyncJobPath), storePath)
+		}
+
+		store, err := loadStore(storePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load target %q store: %w", target.Name, err)
+		}
+
+		targets = append(targets, targetStore{SyncPlanTarget: target, store: store})
 	}
 
-	targetProvider, err := provider.NewClient(cmd.Root().Context(), targetStore)
+	return targets, nil
+}
+
+// sync runs the sync plan once, records it in the audit log if configured, and logs its status.
+// Status is nil if nothing was synced. If the audit log could not be written,
+// status is returned with an error wrapping errAuditFailed.
+func (job *syncJob) sync(ctx context.Context) (*storesync.Status, error) {
+	if syncCmdParams.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, syncCmdParams.Timeout)
+		defer cancel()
+	}
+
+	var resp *storesync.Status
+	var err error
+	if job.singleTarget() {
+		resp, err = storesync.Sync(ctx, *job.source, job.targets[0].Store, job.syncPlan.SyncAction, job.options()...)
+	} else {
+		resp, err = storesync.SyncTargets(ctx, *job.source, job.targets, job.syncPlan.SyncAction, job.options()...)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to create target client: %w", err)
+		err = fmt.Errorf("failed to sync secrets: %w", err)
+	}
+	if resp == nil {
+		return nil, err
+	}
+	var auditErr error
+	if job.syncPlan.AuditLogPath != "" {
+		if err := writeAudit(job, resp); err != nil {
+			auditErr = fmt.Errorf("%w: %w", errAuditFailed, err)
+		}
 	}
 
-	// Init sync request by loading from file and overriding from cli
-	syncPlan, err := loadSyncPlan(syncCmdParams.SyncJobPath)
+	slog.InfoContext(ctx, resp.Status)
+	if len(resp.Collisions) > 0 {
+		slog.WarnContext(ctx, "Some keys were scheduled for sync by multiple actions", slog.Any("collisions", resp.Collisions))
+	}
+	if len(resp.Conflicted) > 0 {
+		slog.WarnContext(ctx, "Some keys were not synced due to concurrent updates", slog.Any("keys", resp.Conflicted))
+	}
+	if len(resp.Unprocessed) > 0 {
+		slog.WarnContext(ctx, "Some keys were not synced because the sync was cancelled", slog.Any("keys", resp.Unprocessed))
+	}
+
+	return resp, errors.Join(err, auditErr)
+}
+
+// writeReport writes sync status report to the output file or stdout, if requested.
+func writeReport(cmd *cobra.Command, status *storesync.Status) error {
+	if syncCmdParams.Output == "" {
+		return nil
+	}
+
+	if syncCmdParams.OutputFile == "" {
+		return writeStatus(cmd.OutOrStdout(), syncCmdParams.Output, status)
+	}
+
+	file, err := os.Create(syncCmdParams.OutputFile)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load sync plan: %w", err)
+		return fmt.Errorf("failed to create sync report file: %w", err)
+	}
+	if err := writeStatus(file, syncCmdParams.Output, status); err != nil {
+		_ = file.Close()
+		return err
 	}
 
-	return &syncJob{
-		source:   &sourceProvider,
-		target:   &targetProvider,
-		syncPlan: syncPlan,
-	}, nil
+	return file.Close()
+}
+
+// options returns sync options defined by the sync plan.
+func (job *syncJob) options() []storesync.Option {
+	return []storesync.Option{
+		storesync.WithConflictPolicy(job.syncPlan.GetConflictPolicy()),
+		storesync.WithFailurePolicy(job.syncPlan.GetFailurePolicy()),
+		storesync.WithSourceLimits(job.syncPlan.SourceLimits),
+		storesync.WithTargetLimits(job.syncPlan.TargetLimits),
+		storesync.WithRetryPolicy(job.syncPlan.RetryPolicy),
+	}
+}
+
+// close releases resources held by source and target store clients.
+func (job *syncJob) close(cmd *cobra.Command) {
+	if err := provider.Close(*job.source); err != nil {
+		slog.WarnContext(cmd.Root().Context(), fmt.Errorf("failed to close source client: %w", err).Error())
+	}
+	for _, target := range job.targets {
+		if err := provider.Close(target.Store.(v1alpha1.StoreClient)); err != nil {
+			slog.WarnContext(cmd.Root().Context(), fmt.Errorf("failed to close target client: %w", err).Error(), slog.String("target", target.Name))
+		}
+	}
+}
+
+// singleTarget checks if the job syncs to a single target given via --target.
+func (job *syncJob) singleTarget() bool {
+	return len(job.targets) == 1 && job.targets[0].Name == ""
 }
 
 func loadStore(path string) (*v1alpha1.SecretStoreSpec, error) {
@@ -15,13 +15,19 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/bank-vaults/secret-sync/pkg/storesync"
 )
 
 const secretStoreTemplate = `
@@ -61,6 +67,276 @@ func TestSync(t *testing.T) {
 	}
 }
 
+func TestSyncExitCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		sync     string
+		wantCode int
+	}{
+		{
+			name: "Success",
+			sync: `
+sync:
+  - secretRef:
+      key: /source/credentials/username
+`,
+		},
+		{
+			name: "Continue on failure",
+			sync: `
+sync:
+  - secretRef:
+      key: /source/credentials/username
+  - secretRef:
+      key: /source/credentials/missing
+`,
+		},
+		{
+			name: "Partial failure",
+			sync: `
+failurePolicy: failAtEnd
+sync:
+  - secretRef:
+      key: /source/credentials/username
+  - secretRef:
+      key: /source/credentials/missing
+`,
+			wantCode: exitCodePartialFailure,
+		},
+		{
+			name: "Aborted",
+			sync: `
+failurePolicy: failFast
+sync:
+  - secretRef:
+      key: /source/credentials/username
+  - secretRef:
+      key: /source/credentials/missing
+`,
+			wantCode: exitCodeAborted,
+		},
+	}
+
+	for _, tt := range tests {
+		ttp := tt
+		t.Run(ttp.name, func(t *testing.T) {
+			syncPath := filepath.Join(t.TempDir(), "syncjob.yaml")
+			require.NoError(t, os.WriteFile(syncPath, []byte(ttp.sync), 0o600))
+
+			t.Cleanup(func() {
+				rootCmd.SetArgs(nil)
+			})
+			rootCmd.SetArgs([]string{
+				"sync",
+				"--source", localStore(t, "testdata"),
+				"--target", localStore(t, t.TempDir()),
+				"--syncjob", syncPath,
+			})
+
+			err := rootCmd.ExecuteContext(context.Background())
+			if ttp.wantCode == 0 {
+				require.NoError(t, err)
+				return
+			}
+
+			require.Error(t, err)
+			assert.Equal(t, ttp.wantCode, exitCode(err))
+		})
+	}
+}
+
+func TestSyncOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		check  func(t *testing.T, report []byte)
+	}{
+		{
+			name:   "JSON",
+			output: outputJSON,
+			check: func(t *testing.T, report []byte) {
+				var status storesync.Status
+				require.NoError(t, json.Unmarshal(report, &status))
+				require.Len(t, status.Results, 1)
+				assert.Equal(t, "/source/credentials/username", status.Results[0].Key)
+				assert.Equal(t, storesync.OutcomeSynced, status.Results[0].Outcome)
+			},
+		},
+		{
+			name:   "YAML",
+			output: outputYAML,
+			check: func(t *testing.T, report []byte) {
+				assert.Contains(t, string(report), "outcome: synced")
+			},
+		},
+		{
+			name:   "JUnit",
+			output: outputJUnit,
+			check: func(t *testing.T, report []byte) {
+				var suites junitTestSuites
+				require.NoError(t, xml.Unmarshal(report, &suites))
+				require.Len(t, suites.Suites, 1)
+				assert.Equal(t, 1, suites.Suites[0].Tests)
+				assert.Zero(t, suites.Suites[0].Failures)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		ttp := tt
+		t.Run(ttp.name, func(t *testing.T) {
+			dir := t.TempDir()
+			syncPath := filepath.Join(dir, "syncjob.yaml")
+			require.NoError(t, os.WriteFile(syncPath, []byte("sync:\n  - secretRef:\n      key: /source/credentials/username\n"), 0o600))
+			reportPath := filepath.Join(dir, "report")
+
+			t.Cleanup(func() {
+				rootCmd.SetArgs(nil)
+				syncCmdParams.Output = ""
+				syncCmdParams.OutputFile = ""
+			})
+			rootCmd.SetArgs([]string{
+				"sync",
+				"--source", localStore(t, "testdata"),
+				"--target", localStore(t, filepath.Join(dir, "target")),
+				"--syncjob", syncPath,
+				"--output", ttp.output,
+				"--output-file", reportPath,
+			})
+			require.NoError(t, rootCmd.ExecuteContext(context.Background()))
+
+			report, err := os.ReadFile(reportPath)
+			require.NoError(t, err)
+			ttp.check(t, report)
+		})
+	}
+}
+
+func TestSyncCancelled(t *testing.T) {
+	dir := t.TempDir()
+	syncPath := filepath.Join(dir, "syncjob.yaml")
+	require.NoError(t, os.WriteFile(syncPath, []byte("sync:\n  - secretRef:\n      key: /source/credentials/username\n"), 0o600))
+	reportPath := filepath.Join(dir, "report")
+
+	t.Cleanup(func() {
+		rootCmd.SetArgs(nil)
+		syncCmdParams.Output = ""
+		syncCmdParams.OutputFile = ""
+	})
+	rootCmd.SetArgs([]string{
+		"sync",
+		"--source", localStore(t, "testdata"),
+		"--target", localStore(t, filepath.Join(dir, "target")),
+		"--syncjob", syncPath,
+		"--output", outputJSON,
+		"--output-file", reportPath,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := rootCmd.ExecuteContext(ctx)
+	require.Error(t, err)
+	assert.Equal(t, exitCodeAborted, exitCode(err))
+
+	// Report is written with actions which were not fetched
+	report, err := os.ReadFile(reportPath)
+	require.NoError(t, err)
+
+	var status storesync.Status
+	require.NoError(t, json.Unmarshal(report, &status))
+	assert.False(t, status.Success)
+	assert.Equal(t, []int{0}, status.Failed)
+}
+
+func TestSyncTargets(t *testing.T) {
+	dir := t.TempDir()
+	syncPath := filepath.Join(dir, "syncjob.yaml")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "primary.yaml"), []byte(fmt.Sprintf(secretStoreTemplate, filepath.Join(dir, "primary"))), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "backup.yaml"), []byte(fmt.Sprintf(secretStoreTemplate, filepath.Join(dir, "backup"))), 0o600))
+	require.NoError(t, os.WriteFile(syncPath, []byte(`
+targets:
+  - name: primary
+    storePath: primary.yaml
+  - name: backup
+    storePath: backup.yaml
+    actions: [1]
+sync:
+  - secretRef:
+      key: /source/credentials/username
+  - secretRef:
+      key: /source/credentials/password
+`), 0o600))
+	reportPath := filepath.Join(dir, "report")
+
+	t.Cleanup(func() {
+		rootCmd.SetArgs(nil)
+		syncCmdParams.TargetStorePath = ""
+		syncCmdParams.Output = ""
+		syncCmdParams.OutputFile = ""
+	})
+	syncCmdParams.TargetStorePath = ""
+	rootCmd.SetArgs([]string{
+		"sync",
+		"--source", localStore(t, "testdata"),
+		"--syncjob", syncPath,
+		"--output", outputJSON,
+		"--output-file", reportPath,
+	})
+	require.NoError(t, rootCmd.ExecuteContext(context.Background()))
+
+	// Each target only receives its actions
+	assert.FileExists(t, filepath.Join(dir, "primary", "source", "credentials", "username"))
+	assert.FileExists(t, filepath.Join(dir, "primary", "source", "credentials", "password"))
+	assert.NoFileExists(t, filepath.Join(dir, "backup", "source", "credentials", "username"))
+	assert.FileExists(t, filepath.Join(dir, "backup", "source", "credentials", "password"))
+
+	report, err := os.ReadFile(reportPath)
+	require.NoError(t, err)
+
+	var status storesync.Status
+	require.NoError(t, json.Unmarshal(report, &status))
+	assert.True(t, status.Success)
+	assert.EqualValues(t, 3, status.Synced)
+	require.Len(t, status.Targets, 2)
+	assert.Equal(t, "primary", status.Targets[0].Name)
+	assert.EqualValues(t, 2, status.Targets[0].Synced)
+	assert.Equal(t, "backup", status.Targets[1].Name)
+	assert.EqualValues(t, 1, status.Targets[1].Synced)
+
+	// Targets cannot be given both via flag and sync plan
+	rootCmd.SetArgs([]string{
+		"sync",
+		"--source", localStore(t, "testdata"),
+		"--target", localStore(t, filepath.Join(dir, "other")),
+		"--syncjob", syncPath,
+	})
+	assert.Error(t, rootCmd.ExecuteContext(context.Background()))
+}
+
+func TestPlan(t *testing.T) {
+	var out bytes.Buffer
+	rootCmd.SetOut(&out)
+	t.Cleanup(func() {
+		rootCmd.SetOut(nil)
+		rootCmd.SetArgs(nil)
+	})
+	rootCmd.SetArgs([]string{
+		"plan",
+		"--source", localStore(t, "testdata"),
+		"--target", localStore(t, t.TempDir()),
+		"--syncjob", "testdata/syncjob.yaml",
+	})
+
+	err := rootCmd.ExecuteContext(context.Background())
+	require.NoError(t, err, "Unexpected error")
+
+	// Nothing exists in target yet
+	assert.Contains(t, out.String(), "create")
+	assert.Contains(t, out.String(), "/target/example-1")
+	assert.Contains(t, out.String(), "0 to update")
+	assert.NotContains(t, out.String(), "SECRET-PREFIX")
+}
+
 func localStore(t *testing.T, dirPath string) string {
 	// Ensure dir exists
 	path, err := filepath.Abs(dirPath)
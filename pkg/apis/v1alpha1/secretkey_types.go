@@ -17,20 +17,29 @@ package v1alpha1
 import "strings"
 
 // SecretRef defines SecretStore reference key.
-// TODO: Add support for version
-// TODO: Add support for map field selector
 // TODO: Add support for encoding
 type SecretRef struct {
 	// Key points to a specific key in store.
-	// Format "path/to/key"
+	// Format "path/to/key". Stores can support additional formats,
+	// e.g. Vault selects a secret field via "path/to/key#field".
 	// Required
 	Key string `json:"key,omitempty"`
 
 	// Version points to specific key version.
+	// If empty, the latest version is used.
 	// Optional
 	Version *string `json:"version,omitempty"`
 }
 
+// GetVersion returns the referenced version or an empty string if the latest version is referenced.
+func (key *SecretRef) GetVersion() string {
+	if key.Version == nil {
+		return ""
+	}
+
+	return *key.Version
+}
+
 // GetPath returns path pointed by Key, e.g. GetPath("/path/to/key") returns ["path", "to"]
 func (key *SecretRef) GetPath() []string {
 	parts := strings.Split(key.sanitizedKey(), "/")
@@ -67,6 +76,31 @@ type SecretQuery struct {
 	// Finds SecretRef based on key query.
 	// Required
 	Key Query `json:"key,omitempty"`
+
+	// Recursive also queries keys in nested paths under Path.
+	// Defaults to false for all stores
+	// Optional
+	Recursive *bool `json:"recursive,omitempty"`
+
+	// MaxDepth limits how many nested path levels under Path are queried
+	// when Recursive is set. If empty, all nested paths are queried.
+	// Optional
+	MaxDepth int `json:"maxDepth,omitempty"`
+}
+
+// IsRecursive checks if keys in nested paths are queried.
+func (query *SecretQuery) IsRecursive() bool {
+	return query.Recursive != nil && *query.Recursive
+}
+
+// IncludesDepth checks if keys nested depth levels under Path are queried,
+// e.g. keys directly under Path have depth 0.
+func (query *SecretQuery) IncludesDepth(depth int) bool {
+	if depth == 0 {
+		return true
+	}
+
+	return query.IsRecursive() && (query.MaxDepth <= 0 || depth <= query.MaxDepth)
 }
 
 // SecretSource defines named secret source.
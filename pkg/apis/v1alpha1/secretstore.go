@@ -19,7 +19,13 @@ import (
 	"errors"
 )
 
-var ErrKeyNotFound = errors.New("secret key not found")
+var (
+	ErrKeyNotFound       = errors.New("secret key not found")
+	ErrKeyVersionDeleted = errors.New("secret key version deleted or destroyed")
+	ErrConflict          = errors.New("secret key was concurrently modified")
+	ErrPermissionDenied  = errors.New("secret key access denied")
+	ErrTransient         = errors.New("secret store temporarily unavailable")
+)
 
 // SecretStore defines methods to manage interaction with secret store.
 type SecretStore interface {
@@ -43,6 +49,10 @@ type StoreReader interface {
 type StoreWriter interface {
 	// SetSecret writes data to a key in a secret store.
 	SetSecret(ctx context.Context, key SecretRef, value []byte) error
+
+	// DeleteSecret removes a key from a secret store.
+	// Returns ErrKeyNotFound if the key does not exist.
+	DeleteSecret(ctx context.Context, key SecretRef) error
 }
 
 // StoreClient unifies read and write ops for a specific secret backend.
@@ -57,4 +67,12 @@ type SecretStoreSpec struct {
 	Vault *VaultStore `json:"vault,omitempty"`
 
 	Local *LocalStore `json:"local,omitempty"`
+
+	Kubernetes *KubernetesStore `json:"kubernetes,omitempty"`
+
+	AWSSecretsManager *AWSSecretsManagerStore `json:"awsSecretsManager,omitempty"`
+
+	GCPSecretManager *GCPSecretManagerStore `json:"gcpSecretManager,omitempty"`
+
+	AzureKeyVault *AzureKeyVaultStore `json:"azureKeyVault,omitempty"`
 }
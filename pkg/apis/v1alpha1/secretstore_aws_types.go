@@ -0,0 +1,76 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+// AWSCredentialsSource defines where AWS credentials are loaded from.
+type AWSCredentialsSource string
+
+const (
+	// AWSCredentialsSourceDefault uses the default AWS SDK credentials chain
+	// (environment, shared config files, instance and container roles).
+	AWSCredentialsSourceDefault AWSCredentialsSource = "default"
+
+	// AWSCredentialsSourceStatic uses credentials from AWSCredentials.
+	AWSCredentialsSourceStatic AWSCredentialsSource = "static"
+
+	// AWSCredentialsSourceProfile uses a named profile from shared config files.
+	AWSCredentialsSourceProfile AWSCredentialsSource = "profile"
+)
+
+// AWSSecretsManagerStore uses AWS Secrets Manager as a backend.
+// Keys are mapped to secret names as "<prefix><path/to/key>".
+type AWSSecretsManagerStore struct {
+	// AWS region of the Secrets Manager.
+	// Required
+	Region string `json:"region"`
+
+	// Overrides the Secrets Manager API endpoint, e.g. for LocalStack.
+	// Optional
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// Defines how to authenticate to AWS.
+	// Defaults to AWSCredentialsSourceDefault
+	// Optional
+	Credentials *AWSCredentials `json:"credentials,omitempty"`
+
+	// Prefix added to all secret names managed by this store, e.g. "team-a/".
+	// Optional
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// AWSCredentials defines AWS credentials source.
+type AWSCredentials struct {
+	// Source of credentials.
+	// Defaults to AWSCredentialsSourceDefault
+	// Optional
+	Source AWSCredentialsSource `json:"source,omitempty"`
+
+	// Used for AWSCredentialsSourceProfile.
+	Profile string `json:"profile,omitempty"`
+
+	// Used for AWSCredentialsSourceStatic.
+	AccessKeyID     string `json:"accessKeyID,omitempty"`
+	SecretAccessKey string `json:"secretAccessKey,omitempty"`
+	SessionToken    string `json:"sessionToken,omitempty"`
+}
+
+// GetSource returns the configured credentials source.
+func (creds *AWSCredentials) GetSource() AWSCredentialsSource {
+	if creds == nil || creds.Source == "" {
+		return AWSCredentialsSourceDefault
+	}
+
+	return creds.Source
+}
@@ -0,0 +1,75 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+// DefaultAzurePathSeparator replaces "/" in keys when mapping them to secret names.
+const DefaultAzurePathSeparator = "--"
+
+// DefaultAzureAuthorityHost is the Microsoft Entra ID endpoint used to obtain access tokens.
+const DefaultAzureAuthorityHost = "https://login.microsoftonline.com"
+
+// AzureKeyVaultStore uses Azure Key Vault as a backend.
+// Since secret names cannot contain "/", keys are mapped to secret names
+// by replacing "/" with PathSeparator, e.g. "/path/to/key" maps to "path--to--key".
+type AzureKeyVaultStore struct {
+	// Key Vault URL, e.g. "https://my-vault.vault.azure.net".
+	// Required
+	VaultURL string `json:"vaultURL"`
+
+	// Microsoft Entra ID tenant of the client.
+	// Required
+	TenantID string `json:"tenantID"`
+
+	// Client (application) ID used for authentication.
+	// Required
+	ClientID string `json:"clientID"`
+
+	// Client secret used for authentication.
+	// Optional, but TokenPath must be provided
+	ClientSecret string `json:"clientSecret,omitempty"`
+
+	// Path to a federated token file used for authentication, e.g. for workload identity.
+	// Optional, but ClientSecret must be provided
+	TokenPath string `json:"tokenPath,omitempty"`
+
+	// Replaces "/" in keys when mapping them to secret names.
+	// Requires at least 2 characters, keys with segments containing it are rejected.
+	// Defaults to DefaultAzurePathSeparator
+	// Optional
+	PathSeparator string `json:"pathSeparator,omitempty"`
+
+	// Overrides the endpoint used to obtain access tokens.
+	// Defaults to DefaultAzureAuthorityHost
+	// Optional
+	AuthorityHost string `json:"authorityHost,omitempty"`
+}
+
+// GetPathSeparator returns the configured path separator.
+func (store *AzureKeyVaultStore) GetPathSeparator() string {
+	if store.PathSeparator == "" {
+		return DefaultAzurePathSeparator
+	}
+
+	return store.PathSeparator
+}
+
+// GetAuthorityHost returns the configured authority host.
+func (store *AzureKeyVaultStore) GetAuthorityHost() string {
+	if store.AuthorityHost == "" {
+		return DefaultAzureAuthorityHost
+	}
+
+	return store.AuthorityHost
+}
@@ -0,0 +1,35 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+// GCPSecretManagerStore uses Google Secret Manager as a backend.
+// Since secret IDs cannot contain "/", keys are mapped to secret IDs
+// by replacing "/" with "--", e.g. "/path/to/key" maps to "path--to--key".
+// Keys with segments containing "--" are rejected.
+type GCPSecretManagerStore struct {
+	// Google Cloud project ID that owns the secrets.
+	// Required
+	ProjectID string `json:"projectID"`
+
+	// Overrides the Secret Manager API endpoint, e.g. for a local fake server.
+	// Requests are not authenticated unless CredentialsPath is also set.
+	// Optional
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// Path to service account credentials file.
+	// If empty, Application Default Credentials are used, unless Endpoint is set.
+	// Optional
+	CredentialsPath string `json:"credentialsPath,omitempty"`
+}
@@ -0,0 +1,29 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+// KubernetesStore uses Kubernetes Secrets as a backend.
+// Keys are mapped as "/<namespace>/<secret-name>/<data-key>".
+type KubernetesStore struct {
+	// Path to kubeconfig file.
+	// If empty, in-cluster config is used.
+	// Optional
+	KubeconfigPath string `json:"kubeconfigPath,omitempty"`
+
+	// Kubeconfig context to use.
+	// If empty, current context is used.
+	// Optional
+	Context string `json:"context,omitempty"`
+}
@@ -14,6 +14,56 @@
 
 package v1alpha1
 
+// VaultKeyMode defines how keys are mapped to Vault secrets.
+type VaultKeyMode string
+
+const (
+	// VaultKeyModeField maps the last key segment to a secret field,
+	// e.g. "/path/to/key" selects the "key" field of the "path/to" secret.
+	// Writes replace the whole secret with a single field.
+	VaultKeyModeField VaultKeyMode = "field"
+
+	// VaultKeyModeSecret maps the key to a whole secret,
+	// e.g. "/path/to/key" selects the "path/to/key" secret.
+	// Reads return the secret fields as a JSON map unless a field is selected
+	// via "/path/to/key#field". Writes store JSON map values as separate fields.
+	VaultKeyModeSecret VaultKeyMode = "secret"
+)
+
+// VaultWriteMode defines how writes are applied to existing Vault secrets.
+type VaultWriteMode string
+
+const (
+	// VaultWriteModeReplace replaces all fields of an existing secret.
+	VaultWriteModeReplace VaultWriteMode = "replace"
+
+	// VaultWriteModeMerge only updates written fields and preserves other fields of an existing secret.
+	// For KV v2, concurrent updates are detected using check-and-set.
+	VaultWriteModeMerge VaultWriteMode = "merge"
+)
+
+// VaultDeleteMode defines how secrets are deleted from KV version 2 engines.
+type VaultDeleteMode string
+
+const (
+	// VaultDeleteModeSoft deletes the latest secret version, which can be undeleted.
+	VaultDeleteModeSoft VaultDeleteMode = "soft"
+
+	// VaultDeleteModeDestroy permanently removes all secret versions and metadata.
+	VaultDeleteModeDestroy VaultDeleteMode = "destroy"
+)
+
+// Default mount paths of Vault auth methods.
+const (
+	DefaultVaultAppRoleMountPath    = "approle"
+	DefaultVaultKubernetesMountPath = "kubernetes"
+	DefaultVaultJWTMountPath        = "jwt"
+	DefaultVaultUserPassMountPath   = "userpass"
+
+	// DefaultVaultKubernetesTokenPath is the service account token mounted into Kubernetes pods.
+	DefaultVaultKubernetesTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+)
+
 // VaultStore uses Hashicorp Vault as a backend.
 type VaultStore struct {
 	Address   string `json:"address"`
@@ -22,4 +72,219 @@ type VaultStore struct {
 	AuthPath  string `json:"authPath"`
 	TokenPath string `json:"tokenPath"`
 	Token     string `json:"token"`
+
+	// Namespace is the Vault Enterprise namespace to use for all requests.
+	// Optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// TLS defines how to connect to Vault over TLS.
+	// Overrides VAULT_* TLS environment variables.
+	// Optional
+	TLS *VaultTLS `json:"tls,omitempty"`
+
+	// Auth defines how to log in to Vault to obtain a token.
+	// If set, Role, AuthPath, TokenPath and Token must be empty.
+	// Optional
+	Auth *VaultAuth `json:"auth,omitempty"`
+
+	// KVVersion defines the version of KV secrets engine mounted at StorePath.
+	// Supported values are 1 and 2. If empty, the version is auto-detected.
+	// Optional
+	KVVersion int `json:"kvVersion,omitempty"`
+
+	// KeyMode defines how keys are mapped to Vault secrets.
+	// Defaults to VaultKeyModeField
+	// Optional
+	KeyMode VaultKeyMode `json:"keyMode,omitempty"`
+
+	// WriteMode defines how writes are applied to existing secrets.
+	// Defaults to VaultWriteModeReplace
+	// Optional
+	WriteMode VaultWriteMode `json:"writeMode,omitempty"`
+
+	// DeleteMode defines how secrets are deleted. Only applies to KV version 2,
+	// secrets are always permanently deleted for KV version 1.
+	// Defaults to VaultDeleteModeSoft
+	// Optional
+	DeleteMode VaultDeleteMode `json:"deleteMode,omitempty"`
+
+	// CheckAndSet enables optimistic concurrency for writes, which fail with
+	// ErrConflict if the secret was modified since the client last read it.
+	// Requires KV version 2.
+	// Optional
+	CheckAndSet bool `json:"checkAndSet,omitempty"`
+}
+
+// GetKeyMode returns the configured key mode.
+func (store *VaultStore) GetKeyMode() VaultKeyMode {
+	if store.KeyMode == "" {
+		return VaultKeyModeField
+	}
+
+	return store.KeyMode
+}
+
+// GetWriteMode returns the configured write mode.
+func (store *VaultStore) GetWriteMode() VaultWriteMode {
+	if store.WriteMode == "" {
+		return VaultWriteModeReplace
+	}
+
+	return store.WriteMode
+}
+
+// GetDeleteMode returns the configured delete mode.
+func (store *VaultStore) GetDeleteMode() VaultDeleteMode {
+	if store.DeleteMode == "" {
+		return VaultDeleteModeSoft
+	}
+
+	return store.DeleteMode
+}
+
+// VaultTLS defines TLS settings for Vault API connections.
+type VaultTLS struct {
+	// CACertPath is a local path to PEM-encoded CA bundle used to verify the server certificate.
+	// Optional
+	CACertPath string `json:"caCertPath,omitempty"`
+
+	// ClientCertPath is a local path to PEM-encoded client certificate for mTLS.
+	// Optional, but ClientKeyPath must be provided
+	ClientCertPath string `json:"clientCertPath,omitempty"`
+
+	// ClientKeyPath is a local path to PEM-encoded client private key for mTLS.
+	// Optional, but ClientCertPath must be provided
+	ClientKeyPath string `json:"clientKeyPath,omitempty"`
+
+	// ServerName is used to verify the server certificate instead of the address host.
+	// Optional
+	ServerName string `json:"serverName,omitempty"`
+
+	// Insecure disables server certificate verification.
+	// Optional
+	Insecure bool `json:"insecure,omitempty"`
+}
+
+// VaultAuth defines Vault auth method used to log in. Exactly one method must be set.
+type VaultAuth struct {
+	// AppRole logs in using a role ID and secret ID.
+	AppRole *VaultAppRoleAuth `json:"appRole,omitempty"`
+
+	// Kubernetes logs in using a Kubernetes service account token.
+	Kubernetes *VaultKubernetesAuth `json:"kubernetes,omitempty"`
+
+	// JWT logs in using a JWT/OIDC token, e.g. issued by a CI provider.
+	JWT *VaultJWTAuth `json:"jwt,omitempty"`
+
+	// UserPass logs in using a username and password.
+	UserPass *VaultUserPassAuth `json:"userpass,omitempty"`
+}
+
+// VaultAppRoleAuth defines AppRole auth method.
+type VaultAppRoleAuth struct {
+	// MountPath of the auth method. Defaults to DefaultVaultAppRoleMountPath
+	// Optional
+	MountPath string `json:"mountPath,omitempty"`
+
+	// RoleID to log in with.
+	// Optional, but RoleIDPath must be provided
+	RoleID string `json:"roleId,omitempty"`
+
+	// RoleIDPath is a local path to file containing the role ID.
+	// Optional, but RoleID must be provided
+	RoleIDPath string `json:"roleIdPath,omitempty"`
+
+	// SecretIDPath is a local path to file containing the secret ID.
+	// Required
+	SecretIDPath string `json:"secretIdPath,omitempty"`
+}
+
+// GetMountPath returns the auth method mount path.
+func (auth *VaultAppRoleAuth) GetMountPath() string {
+	if auth.MountPath == "" {
+		return DefaultVaultAppRoleMountPath
+	}
+
+	return auth.MountPath
+}
+
+// VaultKubernetesAuth defines Kubernetes auth method.
+type VaultKubernetesAuth struct {
+	// MountPath of the auth method. Defaults to DefaultVaultKubernetesMountPath
+	// Optional
+	MountPath string `json:"mountPath,omitempty"`
+
+	// Role to log in with.
+	// Required
+	Role string `json:"role,omitempty"`
+
+	// TokenPath is a local path to service account token. Defaults to DefaultVaultKubernetesTokenPath
+	// Optional
+	TokenPath string `json:"tokenPath,omitempty"`
+}
+
+// GetMountPath returns the auth method mount path.
+func (auth *VaultKubernetesAuth) GetMountPath() string {
+	if auth.MountPath == "" {
+		return DefaultVaultKubernetesMountPath
+	}
+
+	return auth.MountPath
+}
+
+// GetTokenPath returns the service account token path.
+func (auth *VaultKubernetesAuth) GetTokenPath() string {
+	if auth.TokenPath == "" {
+		return DefaultVaultKubernetesTokenPath
+	}
+
+	return auth.TokenPath
+}
+
+// VaultJWTAuth defines JWT/OIDC auth method.
+type VaultJWTAuth struct {
+	// MountPath of the auth method. Defaults to DefaultVaultJWTMountPath
+	// Optional
+	MountPath string `json:"mountPath,omitempty"`
+
+	// Role to log in with. If empty, the default role of the auth method is used.
+	// Optional
+	Role string `json:"role,omitempty"`
+
+	// TokenPath is a local path to file containing the JWT.
+	// Required
+	TokenPath string `json:"tokenPath,omitempty"`
+}
+
+// GetMountPath returns the auth method mount path.
+func (auth *VaultJWTAuth) GetMountPath() string {
+	if auth.MountPath == "" {
+		return DefaultVaultJWTMountPath
+	}
+
+	return auth.MountPath
+}
+
+// VaultUserPassAuth defines userpass auth method.
+type VaultUserPassAuth struct {
+	// MountPath of the auth method. Defaults to DefaultVaultUserPassMountPath
+	// Optional
+	MountPath string `json:"mountPath,omitempty"`
+
+	// Username to log in with.
+	// Required
+	Username string `json:"username,omitempty"`
+
+	// PasswordPath is a local path to file containing the password.
+	// Required
+	PasswordPath string `json:"passwordPath,omitempty"`
+}
+
+// GetMountPath returns the auth method mount path.
+func (auth *VaultUserPassAuth) GetMountPath() string {
+	if auth.MountPath == "" {
+		return DefaultVaultUserPassMountPath
+	}
+
+	return auth.MountPath
 }
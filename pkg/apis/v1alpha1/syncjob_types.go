@@ -14,32 +14,175 @@
 
 package v1alpha1
 
-import (
-	"os"
-	"path/filepath"
+const (
+	DefaultRetryMaxAttempts    = 3
+	DefaultRetryInitialBackoff = "200ms"
+	DefaultRetryMaxBackoff     = "10s"
 )
 
-var DefaultSyncJobAuditLogPath = filepath.Join(os.TempDir(), "sync-audit.log")
+// ConflictPolicy defines how a target key scheduled for sync by multiple actions is handled.
+type ConflictPolicy string
+
+const (
+	// ConflictPolicyFail aborts the sync.
+	ConflictPolicyFail ConflictPolicy = "fail"
+
+	// ConflictPolicyFirstWins syncs the value from the action listed first.
+	ConflictPolicyFirstWins ConflictPolicy = "firstWins"
+
+	// ConflictPolicyLastWinsByOrder syncs the value from the action listed last.
+	ConflictPolicyLastWinsByOrder ConflictPolicy = "lastWinsByOrder"
+
+	// ConflictPolicyMerge merges values which are JSON maps, with keys from
+	// actions listed later taking precedence.
+	ConflictPolicyMerge ConflictPolicy = "merge"
+)
+
+// FailurePolicy defines how failures to fetch or sync keys are handled.
+type FailurePolicy string
+
+const (
+	// FailurePolicyContinue processes all actions and reports failures in sync status.
+	FailurePolicyContinue FailurePolicy = "continue"
+
+	// FailurePolicyFailFast stops the sync on the first failure.
+	FailurePolicyFailFast FailurePolicy = "failFast"
+
+	// FailurePolicyFailAtEnd processes all actions and fails the sync if any of them failed.
+	FailurePolicyFailAtEnd FailurePolicy = "failAtEnd"
+)
 
 // SyncPlan defines overall source-to-target sync strategy.
-// TODO: Add support for auditing.
 type SyncPlan struct {
-	// Points to a file where all sync logs should be saved to.
-	// Defaults to DefaultSyncJobAuditLogPath
+	// Points to a file where a record of every sync operation is appended to.
+	// Records never contain secret values, only their hashes.
+	// If empty, sync operations are not audited.
 	// Optional
 	AuditLogPath string `json:"auditLogPath,omitempty"`
 
+	// Used to specify how keys scheduled for sync by multiple actions are handled.
+	// Accepts fail, firstWins, lastWinsByOrder, merge.
+	// Defaults to fail
+	// Optional
+	ConflictPolicy ConflictPolicy `json:"conflictPolicy,omitempty"`
+
+	// Used to specify how failures to fetch or sync keys are handled.
+	// Accepts continue, failFast, failAtEnd.
+	// Defaults to continue
+	// Optional
+	FailurePolicy FailurePolicy `json:"failurePolicy,omitempty"`
+
+	// Used to limit requests made to the source store.
+	// Optional
+	SourceLimits *StoreLimits `json:"sourceLimits,omitempty"`
+
+	// Used to limit requests made to the target store.
+	// Optional
+	TargetLimits *StoreLimits `json:"targetLimits,omitempty"`
+
+	// Used to retry store requests which failed due to temporary errors.
+	// If empty, requests are not retried.
+	// Optional
+	RetryPolicy *RetryPolicy `json:"retryPolicy,omitempty"`
+
+	// Used to sync to multiple target stores instead of the one given on the command line.
+	// Values are fetched from source only once for all targets.
+	// Optional
+	Targets []SyncPlanTarget `json:"targets,omitempty"`
+
 	// Used to specify the strategy for secrets sync.
 	// Required
 	SyncAction []SyncAction `json:"sync,omitempty"`
 }
 
-func (spec *SyncPlan) GetAuditLogPath() string {
-	if spec.AuditLogPath == "" {
-		return DefaultSyncJobAuditLogPath
+func (spec *SyncPlan) GetConflictPolicy() ConflictPolicy {
+	if spec.ConflictPolicy == "" {
+		return ConflictPolicyFail
+	}
+
+	return spec.ConflictPolicy
+}
+
+func (spec *SyncPlan) GetFailurePolicy() FailurePolicy {
+	if spec.FailurePolicy == "" {
+		return FailurePolicyContinue
+	}
+
+	return spec.FailurePolicy
+}
+
+// SyncPlanTarget defines a target store of a sync plan.
+type SyncPlanTarget struct {
+	// Identifies the target in sync status and audit log.
+	// Required
+	Name string `json:"name"`
+
+	// Points to the target store config file.
+	// Relative paths are resolved against the sync plan file directory.
+	// Required
+	StorePath string `json:"storePath"`
+
+	// Indexes of sync actions synced to this target.
+	// Defaults to all actions
+	// Optional
+	Actions []int `json:"actions,omitempty"`
+}
+
+// StoreLimits defines how many requests can be made to a store.
+// Limits apply to reads, lists, writes, and deletes.
+type StoreLimits struct {
+	// Maximum number of concurrent requests.
+	// Defaults to 0 (unlimited)
+	// Optional
+	MaxConcurrency int `json:"maxConcurrency,omitempty"`
+
+	// Maximum number of requests per second, e.g. 0.5 allows one request every two seconds.
+	// Defaults to 0 (unlimited)
+	// Optional
+	RequestsPerSecond float64 `json:"requestsPerSecond,omitempty"`
+}
+
+// RetryPolicy defines how store requests which failed due to temporary errors are retried.
+// The delay between attempts grows exponentially with random jitter.
+type RetryPolicy struct {
+	// Maximum number of attempts for each request, including the first one.
+	// Defaults to DefaultRetryMaxAttempts
+	// Optional
+	MaxAttempts int `json:"maxAttempts,omitempty"`
+
+	// Delay before the first retry, doubled for every next retry, e.g. "500ms".
+	// Defaults to DefaultRetryInitialBackoff
+	// Optional
+	InitialBackoff string `json:"initialBackoff,omitempty"`
+
+	// Maximum delay between attempts, e.g. "30s".
+	// Defaults to DefaultRetryMaxBackoff
+	// Optional
+	MaxBackoff string `json:"maxBackoff,omitempty"`
+}
+
+func (policy *RetryPolicy) GetMaxAttempts() int {
+	if policy.MaxAttempts == 0 {
+		return DefaultRetryMaxAttempts
+	}
+
+	return policy.MaxAttempts
+}
+
+func (policy *RetryPolicy) GetInitialBackoff() string {
+	if policy.InitialBackoff == "" {
+		return DefaultRetryInitialBackoff
 	}
 
-	return spec.AuditLogPath
+	return policy.InitialBackoff
+}
+
+func (policy *RetryPolicy) GetMaxBackoff() string {
+	if policy.MaxBackoff == "" {
+		return DefaultRetryMaxBackoff
+	}
+
+	return policy.MaxBackoff
 }
 
 // SyncAction defines how to fetch, transform, and sync SecretRef(s) from source to target.
@@ -67,6 +210,24 @@ type SyncAction struct {
 	// Flatten indicates secrets FromQuery will be synced to a single SyncTarget.Key.
 	Flatten *bool `json:"flatten,omitempty"`
 
+	// Prune indicates that target keys under SyncTarget.KeyPrefix which were not synced
+	// from FromQuery will be deleted, so that the target mirrors the source.
+	// Requires FromQuery and SyncTarget.KeyPrefix.
+	Prune *bool `json:"prune,omitempty"`
+
+	// ConflictPolicy overrides SyncPlan.ConflictPolicy for keys which this action
+	// schedules for sync after an action listed before it.
+	// Optional
+	ConflictPolicy ConflictPolicy `json:"conflictPolicy,omitempty"`
+
+	// Timeout limits how long fetching secrets for this action and writing
+	// each of its keys can take, e.g. "30s".
+	// Secrets read by several actions are fetched once, so a timed out action
+	// stops waiting for the fetch, but the fetch continues for other actions.
+	// Defaults to no timeout
+	// Optional
+	Timeout string `json:"timeout,omitempty"`
+
 	// Template defines how the fetched key(s) will be transformed to create a new
 	// SecretRef that will be synced to target.
 	// When using FromRef, {{ .Data }} defines given secrets raw value.
@@ -0,0 +1,271 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package audit records sync operations in a tamper-evident log.
+// Each record holds the hash of the previous one, so modifying, removing,
+// or reordering records breaks the chain and can be detected by Verify.
+// Records removed from the end of the log leave the chain intact, so detecting
+// truncation requires comparing the last hash with a copy kept elsewhere.
+// Secret values are never recorded, only their SHA-256 hashes, so that
+// records of the same key can be compared across syncs to tell if its value changed.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+	"unicode"
+
+	"github.com/bank-vaults/secret-sync/pkg/apis/v1alpha1"
+	"github.com/bank-vaults/secret-sync/pkg/storesync"
+)
+
+// ErrChainBroken is returned by Verify when the log was modified.
+var ErrChainBroken = errors.New("audit log chain is broken")
+
+// Record defines a single audited sync operation.
+type Record struct {
+	Timestamp  time.Time            `json:"timestamp"`
+	Job        string               `json:"job"`
+	Action     int                  `json:"action"`
+	Target     string               `json:"target,omitempty"` // target name, empty for a single target
+	SourceRefs []v1alpha1.SecretRef `json:"sourceRefs,omitempty"`
+	Key        string               `json:"key,omitempty"` // target key, empty if action could not be fetched
+	Outcome    storesync.Outcome    `json:"outcome"`
+	Error      string               `json:"error,omitempty"`
+	ValueHash  string               `json:"valueHash,omitempty"` // SHA-256 of the synced value
+	PrevHash   string               `json:"prevHash"`
+	Hash       string               `json:"hash"`
+}
+
+// digest returns the hash of a record which chains it to the previous one.
+func (r Record) digest() (string, error) {
+	r.Hash = ""
+	data, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// NewRecords returns audit records for all operations of a sync job.
+func NewRecords(job string, status *storesync.Status) []Record {
+	timestamp := status.SyncedAt.UTC()
+
+	records := make([]Record, 0, len(status.Failed)+len(status.Results))
+	for _, id := range status.Failed {
+		records = append(records, Record{
+			Timestamp: timestamp,
+			Job:       job,
+			Action:    id,
+			Outcome:   storesync.OutcomeFailed,
+			Error:     "failed to fetch sync action",
+		})
+	}
+	for _, result := range status.Results {
+		records = append(records, Record{
+			Timestamp:  timestamp,
+			Job:        job,
+			Action:     result.RequestID,
+			Target:     result.Target,
+			SourceRefs: result.SourceRefs,
+			Key:        result.Key,
+			Outcome:    result.Outcome,
+			Error:      result.Error,
+			ValueHash:  result.ValueDigest,
+		})
+	}
+
+	return records
+}
+
+// Log appends records to an audit log file.
+type Log struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// Open opens an audit log file for appending, creating it if needed.
+func Open(path string) (*Log, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create audit log dir: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+
+	return &Log{file: file}, nil
+}
+
+// Write appends records to the log and flushes them to disk.
+// New records continue the chain of records already in the file.
+// The file is locked while writing, so that processes sharing the log do not break the chain.
+func (l *Log) Write(records ...Record) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := syscall.Flock(int(l.file.Fd()), syscall.LOCK_EX); err != nil {
+		return fmt.Errorf("failed to lock audit log: %w", err)
+	}
+	defer func() { _ = syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN) }()
+
+	lastHash, err := readLastHash(l.file)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	for _, record := range records {
+		record.PrevHash = lastHash
+		hash, err := record.digest()
+		if err != nil {
+			return fmt.Errorf("failed to hash audit record: %w", err)
+		}
+		record.Hash = hash
+
+		line, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("failed to marshal audit record: %w", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+		lastHash = hash
+	}
+
+	if _, err := l.file.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("failed to flush audit log: %w", err)
+	}
+
+	return nil
+}
+
+// Close closes the log file.
+func (l *Log) Close() error {
+	return l.file.Close()
+}
+
+// Verify checks that records read from r form an unbroken chain.
+// Returns the number of verified records.
+func Verify(r io.Reader) (int, error) {
+	var count int
+	lastHash := ""
+	err := readRecords(r, func(line int, record Record) error {
+		if record.PrevHash != lastHash {
+			return fmt.Errorf("%w: record on line %d does not follow the previous record", ErrChainBroken, line)
+		}
+
+		hash, err := record.digest()
+		if err != nil {
+			return fmt.Errorf("failed to hash record on line %d: %w", line, err)
+		}
+		if record.Hash != hash {
+			return fmt.Errorf("%w: record on line %d was modified", ErrChainBroken, line)
+		}
+
+		lastHash = record.Hash
+		count++
+		return nil
+	})
+
+	return count, err
+}
+
+// readLastHash returns the hash of the last record in the log, or empty string if the log is empty.
+// Only the last record is read, so that appending does not slow down as the log grows.
+func readLastHash(file *os.File) (string, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	line, err := readLastLine(file, info.Size())
+	if err != nil {
+		return "", err
+	}
+	if len(line) == 0 {
+		return "", nil
+	}
+
+	var record Record
+	if err := json.Unmarshal(line, &record); err != nil {
+		return "", fmt.Errorf("%w: invalid last record: %w", ErrChainBroken, err)
+	}
+
+	return record.Hash, nil
+}
+
+// readLastLine returns the last non-empty line of r, reading it backwards from size in chunks.
+func readLastLine(r io.ReaderAt, size int64) ([]byte, error) {
+	const chunkSize = 4096
+
+	var data []byte
+	for end := size; end > 0; {
+		start := max(end-chunkSize, 0)
+		chunk := make([]byte, end-start)
+		if _, err := r.ReadAt(chunk, start); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("failed to read audit log: %w", err)
+		}
+		data = append(chunk, data...)
+		end = start
+
+		trimmed := bytes.TrimRightFunc(data, unicode.IsSpace)
+		if i := bytes.LastIndexByte(trimmed, '\n'); i >= 0 {
+			return bytes.TrimSpace(trimmed[i+1:]), nil
+		}
+	}
+
+	return bytes.TrimSpace(data), nil
+}
+
+// readRecords calls fn for each record read from r, skipping empty lines.
+func readRecords(r io.Reader, fn func(line int, record Record) error) error {
+	reader := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to read audit log: %w", err)
+		}
+
+		if data = bytes.TrimSpace(data); len(data) > 0 {
+			var record Record
+			if err := json.Unmarshal(data, &record); err != nil {
+				return fmt.Errorf("%w: invalid record on line %d: %w", ErrChainBroken, line, err)
+			}
+			if err := fn(line, record); err != nil {
+				return err
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bank-vaults/secret-sync/pkg/apis/v1alpha1"
+	"github.com/bank-vaults/secret-sync/pkg/storesync"
+)
+
+func TestLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "sync.log")
+	status := &storesync.Status{
+		Failed: []int{1},
+		Results: []storesync.KeyResult{
+			{
+				RequestID:   0,
+				SourceRefs:  []v1alpha1.SecretRef{{Key: "/source/a"}},
+				Key:         "/target/a",
+				Outcome:     storesync.OutcomeSynced,
+				ValueDigest: "2e7d2c03a9507ae265ecf5b5356885a53393a2029d241394997265a1a25aefc6",
+			},
+		},
+		SyncedAt: time.Now(),
+	}
+
+	// Records are chained across runs
+	for range 2 {
+		log, err := Open(path)
+		require.NoError(t, err)
+		require.NoError(t, log.Write(NewRecords("syncjob.yaml", status)...))
+		require.NoError(t, log.Close())
+	}
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "value\"")
+
+	count, err := Verify(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, 4, count)
+
+	lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+	require.Len(t, lines, 4)
+
+	tests := []struct {
+		name   string
+		tamper func(lines [][]byte) [][]byte
+	}{
+		{
+			name: "Modified record",
+			tamper: func(lines [][]byte) [][]byte {
+				lines[1] = bytes.Replace(lines[1], []byte("/target/a"), []byte("/target/b"), 1)
+				return lines
+			},
+		},
+		{
+			name: "Removed record",
+			tamper: func(lines [][]byte) [][]byte {
+				return append(lines[:1], lines[2:]...)
+			},
+		},
+		{
+			name: "Reordered records",
+			tamper: func(lines [][]byte) [][]byte {
+				lines[0], lines[1] = lines[1], lines[0]
+				return lines
+			},
+		},
+		{
+			name: "Invalid record",
+			tamper: func(lines [][]byte) [][]byte {
+				lines[2] = []byte("{")
+				return lines
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		ttp := tt
+		t.Run(ttp.name, func(t *testing.T) {
+			tampered := ttp.tamper(bytes.Split(bytes.Clone(bytes.TrimSpace(data)), []byte("\n")))
+
+			_, err := Verify(bytes.NewReader(bytes.Join(tampered, []byte("\n"))))
+			assert.ErrorIs(t, err, ErrChainBroken)
+		})
+	}
+}
+
+func TestLogConcurrentWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sync.log")
+	status := &storesync.Status{
+		Results: []storesync.KeyResult{
+			{Key: "/target/a", Outcome: storesync.OutcomeSynced},
+			{Key: "/target/b", Outcome: storesync.OutcomeUnchanged},
+		},
+		SyncedAt: time.Now(),
+	}
+
+	// Each writer opens the log separately, as concurrent sync runs do
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			log, err := Open(path)
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer log.Close()
+
+			for range 5 {
+				assert.NoError(t, log.Write(NewRecords("syncjob.yaml", status)...))
+			}
+		}()
+	}
+	wg.Wait()
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	count, err := Verify(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, 80, count)
+}
+
+func TestLogInvalidLastRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sync.log")
+	require.NoError(t, os.WriteFile(path, []byte("{\"hash\":\"a\"}\n{\n\n"), 0o600))
+
+	log, err := Open(path)
+	require.NoError(t, err)
+	defer log.Close()
+
+	// Records are not appended to a chain which cannot be continued
+	err = log.Write(Record{Key: "/target/a", Outcome: storesync.OutcomeSynced})
+	assert.ErrorIs(t, err, ErrChainBroken)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "/target/a")
+}
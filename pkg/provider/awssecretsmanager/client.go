@@ -0,0 +1,221 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awssecretsmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager/types"
+	"github.com/aws/smithy-go"
+
+	"github.com/bank-vaults/secret-sync/pkg/apis/v1alpha1"
+)
+
+type client struct {
+	apiClient *secretsmanager.Client
+	prefix    string
+}
+
+func (c *client) GetSecret(ctx context.Context, key v1alpha1.SecretRef) ([]byte, error) {
+	name := c.nameForKey(key)
+
+	// Get secret from API
+	response, err := c.apiClient.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(name),
+	})
+	if err != nil {
+		if isNotFound(err) || c.isMarkedForDeletion(ctx, name, err) {
+			return nil, v1alpha1.ErrKeyNotFound
+		}
+
+		return nil, fmt.Errorf("api get request failed: %w", classifyError(err))
+	}
+
+	// Extract value
+	if response.SecretString != nil {
+		return []byte(*response.SecretString), nil
+	}
+	if response.SecretBinary != nil {
+		return response.SecretBinary, nil
+	}
+
+	return nil, errors.New("api get returned empty data")
+}
+
+func (c *client) ListSecretKeys(ctx context.Context, query v1alpha1.SecretQuery) ([]v1alpha1.SecretRef, error) {
+	// Get name prefix to query
+	namePrefix := c.prefix
+	if query.Path != nil {
+		if queryPath := strings.Trim(*query.Path, "/"); queryPath != "" {
+			namePrefix += queryPath + "/"
+		}
+	}
+
+	listInput := &secretsmanager.ListSecretsInput{}
+	if namePrefix != "" {
+		listInput.Filters = []types.Filter{
+			{
+				Key:    types.FilterNameStringTypeName,
+				Values: []string{namePrefix},
+			},
+		}
+	}
+
+	// List API request, following pages
+	var result []v1alpha1.SecretRef
+	pages := secretsmanager.NewListSecretsPaginator(c.apiClient, listInput)
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("api list request failed: %w", classifyError(err))
+		}
+
+		for _, entry := range page.SecretList {
+			name := aws.ToString(entry.Name)
+			if !strings.HasPrefix(name, namePrefix) {
+				continue
+			}
+
+			// Skip secrets in nested paths which are not queried
+			keyPath := strings.TrimPrefix(name, namePrefix)
+			if keyPath == "" || !query.IncludesDepth(strings.Count(keyPath, "/")) {
+				continue
+			}
+			keyName := keyPath[strings.LastIndex(keyPath, "/")+1:]
+
+			// Add key if it matches regexp query
+			if matches, _ := regexp.MatchString(query.Key.Regexp, keyName); matches {
+				result = append(result, v1alpha1.SecretRef{
+					Key: "/" + strings.TrimPrefix(name, c.prefix),
+				})
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func (c *client) SetSecret(ctx context.Context, key v1alpha1.SecretRef, value []byte) error {
+	name := c.nameForKey(key)
+
+	// Update secret value
+	putInput := &secretsmanager.PutSecretValueInput{
+		SecretId: aws.String(name),
+	}
+	if utf8.Valid(value) {
+		putInput.SecretString = aws.String(string(value))
+	} else {
+		putInput.SecretBinary = value
+	}
+
+	_, err := c.apiClient.PutSecretValue(ctx, putInput)
+	if c.isMarkedForDeletion(ctx, name, err) {
+		// Deleted secrets keep their name until the recovery window ends, restore to write a new version
+		if _, err := c.apiClient.RestoreSecret(ctx, &secretsmanager.RestoreSecretInput{SecretId: aws.String(name)}); err != nil {
+			return fmt.Errorf("api restore request failed: %w", classifyError(err))
+		}
+		_, err = c.apiClient.PutSecretValue(ctx, putInput)
+	}
+	if err == nil {
+		return nil
+	}
+	if !isNotFound(err) {
+		return fmt.Errorf("api set request failed: %w", classifyError(err))
+	}
+
+	// Create secret if it does not exist
+	_, err = c.apiClient.CreateSecret(ctx, &secretsmanager.CreateSecretInput{
+		Name:         aws.String(name),
+		SecretString: putInput.SecretString,
+		SecretBinary: putInput.SecretBinary,
+	})
+	if err != nil {
+		return fmt.Errorf("api create request failed: %w", classifyError(err))
+	}
+
+	return nil
+}
+
+func (c *client) DeleteSecret(ctx context.Context, key v1alpha1.SecretRef) error {
+	name := c.nameForKey(key)
+
+	// Secret is scheduled for deletion using the default recovery window.
+	// Deleted secrets are restored by SetSecret if the key is synced again.
+	_, err := c.apiClient.DeleteSecret(ctx, &secretsmanager.DeleteSecretInput{
+		SecretId: aws.String(name),
+	})
+	if err != nil {
+		if isNotFound(err) || c.isMarkedForDeletion(ctx, name, err) {
+			return v1alpha1.ErrKeyNotFound
+		}
+
+		return fmt.Errorf("api delete request failed: %w", classifyError(err))
+	}
+
+	return nil
+}
+
+// isMarkedForDeletion checks if a request failed because the secret is scheduled for deletion.
+// Such secrets are not listed, and cannot be read or written until restored.
+func (c *client) isMarkedForDeletion(ctx context.Context, name string, err error) bool {
+	var invalidErr *types.InvalidRequestException
+	if !errors.As(err, &invalidErr) {
+		return false
+	}
+
+	secret, err := c.apiClient.DescribeSecret(ctx, &secretsmanager.DescribeSecretInput{
+		SecretId: aws.String(name),
+	})
+	return err == nil && secret.DeletedDate != nil
+}
+
+// nameForKey returns secret name for a key, e.g. nameForKey("/path/to/key") returns "<prefix>path/to/key".
+func (c *client) nameForKey(key v1alpha1.SecretRef) string {
+	return c.prefix + strings.Join(append(key.GetPath(), key.GetName()), "/")
+}
+
+func isNotFound(err error) bool {
+	var notFoundErr *types.ResourceNotFoundException
+	return errors.As(err, &notFoundErr)
+}
+
+// classifyError marks API errors caused by missing permissions or likely temporary failures.
+func classifyError(err error) error {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode() == "AccessDeniedException" {
+		return fmt.Errorf("%w: %w", v1alpha1.ErrPermissionDenied, err)
+	}
+
+	if retry.IsErrorRetryables(retry.DefaultRetryables).IsErrorRetryable(err) == aws.TrueTernary {
+		return fmt.Errorf("%w: %w", v1alpha1.ErrTransient, err)
+	}
+
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) && (respErr.HTTPStatusCode() == http.StatusTooManyRequests || respErr.HTTPStatusCode() >= http.StatusInternalServerError) {
+		return fmt.Errorf("%w: %w", v1alpha1.ErrTransient, err)
+	}
+
+	return err
+}
@@ -0,0 +1,296 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awssecretsmanager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bank-vaults/secret-sync/pkg/apis/v1alpha1"
+)
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeServer(t, map[string]string{
+		"team/db/username":  "admin",
+		"team/db/password":  "secret",
+		"team/db/nested/id": "nested",
+		"other/db/username": "other",
+	})
+	storeClient := newTestClient(t, server)
+
+	// Get
+	value, err := storeClient.GetSecret(ctx, v1alpha1.SecretRef{Key: "/db/username"})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("admin"), value)
+
+	_, err = storeClient.GetSecret(ctx, v1alpha1.SecretRef{Key: "/db/missing"})
+	assert.ErrorIs(t, err, v1alpha1.ErrKeyNotFound)
+
+	// List
+	path := "/db"
+	refs, err := storeClient.ListSecretKeys(ctx, v1alpha1.SecretQuery{
+		Path: &path,
+		Key:  v1alpha1.Query{Regexp: "user.*|pass.*"},
+	})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []v1alpha1.SecretRef{{Key: "/db/username"}, {Key: "/db/password"}}, refs)
+
+	// Set (update and create)
+	require.NoError(t, storeClient.SetSecret(ctx, v1alpha1.SecretRef{Key: "/db/password"}, []byte("changed")))
+	require.NoError(t, storeClient.SetSecret(ctx, v1alpha1.SecretRef{Key: "/db/host"}, []byte("localhost")))
+
+	value, err = storeClient.GetSecret(ctx, v1alpha1.SecretRef{Key: "/db/password"})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("changed"), value)
+
+	value, err = storeClient.GetSecret(ctx, v1alpha1.SecretRef{Key: "/db/host"})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("localhost"), value)
+
+	// Delete
+	require.NoError(t, storeClient.DeleteSecret(ctx, v1alpha1.SecretRef{Key: "/db/host"}))
+	_, err = storeClient.GetSecret(ctx, v1alpha1.SecretRef{Key: "/db/host"})
+	assert.ErrorIs(t, err, v1alpha1.ErrKeyNotFound)
+
+	err = storeClient.DeleteSecret(ctx, v1alpha1.SecretRef{Key: "/db/host"})
+	assert.ErrorIs(t, err, v1alpha1.ErrKeyNotFound)
+
+	// Deleted secrets are not listed
+	refs, err = storeClient.ListSecretKeys(ctx, v1alpha1.SecretQuery{Path: &path, Key: v1alpha1.Query{Regexp: "host"}})
+	require.NoError(t, err)
+	assert.Empty(t, refs)
+
+	// Deleted secret is restored when set again
+	require.NoError(t, storeClient.SetSecret(ctx, v1alpha1.SecretRef{Key: "/db/host"}, []byte("remote")))
+	value, err = storeClient.GetSecret(ctx, v1alpha1.SecretRef{Key: "/db/host"})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("remote"), value)
+	assert.Empty(t, server.deleted)
+}
+
+func TestClientNoSDKRetries(t *testing.T) {
+	server := newFakeServer(t, map[string]string{"team/db/username": "admin"})
+	server.unavailable = true
+	storeClient := newTestClient(t, server)
+
+	// Failed requests are left to the sync retry policy
+	_, err := storeClient.GetSecret(context.Background(), v1alpha1.SecretRef{Key: "/db/username"})
+	assert.ErrorIs(t, err, v1alpha1.ErrTransient)
+	assert.Equal(t, 1, server.requests)
+}
+
+// newTestClient creates a client for the fake server through the provider.
+func newTestClient(t *testing.T, server *fakeServer) v1alpha1.StoreClient {
+	storeClient, err := (&Provider{}).NewClient(context.Background(), v1alpha1.SecretStoreSpec{
+		AWSSecretsManager: &v1alpha1.AWSSecretsManagerStore{
+			Region:   "eu-west-1",
+			Endpoint: server.URL,
+			Prefix:   "team/",
+			Credentials: &v1alpha1.AWSCredentials{
+				Source:          v1alpha1.AWSCredentialsSourceStatic,
+				AccessKeyID:     "id",
+				SecretAccessKey: "secret",
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	return storeClient
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantErr error
+	}{
+		{name: "Access denied", err: &smithy.GenericAPIError{Code: "AccessDeniedException"}, wantErr: v1alpha1.ErrPermissionDenied},
+		{name: "Throttled", err: &smithy.GenericAPIError{Code: "ThrottlingException"}, wantErr: v1alpha1.ErrTransient},
+		{name: "Unavailable", err: newResponseError(http.StatusServiceUnavailable, "ServiceUnavailable"), wantErr: v1alpha1.ErrTransient},
+		{name: "Too many requests", err: newResponseError(http.StatusTooManyRequests, "TooManyRequests"), wantErr: v1alpha1.ErrTransient},
+		{name: "Bad request", err: newResponseError(http.StatusBadRequest, "ValidationException")},
+		{name: "Connection refused", err: &smithy.OperationError{Err: &url.Error{Op: "Post", URL: "https://store", Err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}}}, wantErr: v1alpha1.ErrTransient},
+		{name: "Cancelled", err: &smithy.OperationError{Err: &smithy.CanceledError{Err: context.Canceled}}},
+	}
+
+	for _, tt := range tests {
+		ttp := tt
+		t.Run(ttp.name, func(t *testing.T) {
+			err := classifyError(ttp.err)
+			assert.ErrorIs(t, err, ttp.err)
+			if ttp.wantErr == nil {
+				assert.False(t, errors.Is(err, v1alpha1.ErrPermissionDenied) || errors.Is(err, v1alpha1.ErrTransient))
+				return
+			}
+			assert.ErrorIs(t, err, ttp.wantErr)
+		})
+	}
+}
+
+// newResponseError returns an API error as returned for an HTTP response with given status.
+func newResponseError(status int, code string) error {
+	return &awshttp.ResponseError{
+		ResponseError: &smithyhttp.ResponseError{
+			Response: &smithyhttp.Response{Response: &http.Response{StatusCode: status}},
+			Err:      &smithy.GenericAPIError{Code: code},
+		},
+	}
+}
+
+// fakeServer is a minimal Secrets Manager API stand-in.
+type fakeServer struct {
+	*httptest.Server
+	mu          sync.Mutex
+	secrets     map[string]string
+	deleted     map[string]string // secrets scheduled for deletion which can be restored
+	unavailable bool              // fail all requests with a temporary error
+	requests    int
+}
+
+// newFakeServer creates a minimal Secrets Manager API stand-in.
+func newFakeServer(t *testing.T, secrets map[string]string) *fakeServer {
+	fake := &fakeServer{secrets: secrets, deleted: map[string]string{}}
+	fake.Server = httptest.NewServer(http.HandlerFunc(fake.handle))
+	t.Cleanup(fake.Close)
+
+	return fake
+}
+
+func (s *fakeServer) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests++
+	if s.unavailable {
+		writeError(w, http.StatusServiceUnavailable, "ServiceUnavailable")
+		return
+	}
+
+	var input struct {
+		SecretID     string `json:"SecretId"`
+		Name         string `json:"Name"`
+		SecretString string `json:"SecretString"`
+		Filters      []struct {
+			Values []string `json:"Values"`
+		} `json:"Filters"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Secrets scheduled for deletion can only be described or restored
+	operation := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "secretsmanager.")
+	if _, ok := s.deleted[input.SecretID+input.Name]; ok && operation != "DescribeSecret" && operation != "RestoreSecret" {
+		writeError(w, http.StatusBadRequest, "InvalidRequestException")
+		return
+	}
+
+	var output any
+	switch operation {
+	case "GetSecretValue":
+		value, ok := s.secrets[input.SecretID]
+		if !ok {
+			writeError(w, http.StatusBadRequest, "ResourceNotFoundException")
+			return
+		}
+		output = map[string]string{"Name": input.SecretID, "SecretString": value}
+
+	case "PutSecretValue":
+		if _, ok := s.secrets[input.SecretID]; !ok {
+			writeError(w, http.StatusBadRequest, "ResourceNotFoundException")
+			return
+		}
+		s.secrets[input.SecretID] = input.SecretString
+		output = map[string]string{"Name": input.SecretID}
+
+	case "DeleteSecret":
+		value, ok := s.secrets[input.SecretID]
+		if !ok {
+			writeError(w, http.StatusBadRequest, "ResourceNotFoundException")
+			return
+		}
+		delete(s.secrets, input.SecretID)
+		s.deleted[input.SecretID] = value
+		output = map[string]string{"Name": input.SecretID}
+
+	case "DescribeSecret":
+		if _, ok := s.deleted[input.SecretID]; ok {
+			output = map[string]any{"Name": input.SecretID, "DeletedDate": 1700000000}
+		} else if _, ok := s.secrets[input.SecretID]; ok {
+			output = map[string]any{"Name": input.SecretID}
+		} else {
+			writeError(w, http.StatusBadRequest, "ResourceNotFoundException")
+			return
+		}
+
+	case "RestoreSecret":
+		value, ok := s.deleted[input.SecretID]
+		if !ok {
+			writeError(w, http.StatusBadRequest, "InvalidRequestException")
+			return
+		}
+		delete(s.deleted, input.SecretID)
+		s.secrets[input.SecretID] = value
+		output = map[string]string{"Name": input.SecretID}
+
+	case "CreateSecret":
+		s.secrets[input.Name] = input.SecretString
+		output = map[string]string{"Name": input.Name}
+
+	case "ListSecrets":
+		var entries []map[string]string
+		for name := range s.secrets {
+			if len(input.Filters) == 0 || strings.HasPrefix(name, input.Filters[0].Values[0]) {
+				entries = append(entries, map[string]string{"Name": name})
+			}
+		}
+		sort.Slice(entries, func(i, j int) bool { return entries[i]["Name"] < entries[j]["Name"] })
+		output = map[string]any{"SecretList": entries}
+
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	_ = json.NewEncoder(w).Encode(output)
+}
+
+func writeError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"__type":  code,
+		"message": "request failed",
+	})
+}
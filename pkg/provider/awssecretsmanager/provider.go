@@ -0,0 +1,104 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package awssecretsmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+
+	"github.com/bank-vaults/secret-sync/pkg/apis/v1alpha1"
+)
+
+type Provider struct{}
+
+func (p *Provider) NewClient(ctx context.Context, backend v1alpha1.SecretStoreSpec) (v1alpha1.StoreClient, error) {
+	store := backend.AWSSecretsManager
+
+	// Requests are retried by the sync retry policy, do not retry them in the SDK as well
+	configOpts := []func(*config.LoadOptions) error{
+		config.WithRegion(store.Region),
+		config.WithRetryer(func() aws.Retryer {
+			return aws.NopRetryer{}
+		}),
+	}
+
+	switch store.Credentials.GetSource() {
+	case v1alpha1.AWSCredentialsSourceStatic:
+		configOpts = append(configOpts, config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
+			store.Credentials.AccessKeyID,
+			store.Credentials.SecretAccessKey,
+			store.Credentials.SessionToken,
+		)))
+	case v1alpha1.AWSCredentialsSourceProfile:
+		configOpts = append(configOpts, config.WithSharedConfigProfile(store.Credentials.Profile))
+	}
+
+	apiConfig, err := config.LoadDefaultConfig(ctx, configOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load aws config: %w", err)
+	}
+
+	apiClient := secretsmanager.NewFromConfig(apiConfig, func(opts *secretsmanager.Options) {
+		if store.Endpoint != "" {
+			opts.BaseEndpoint = aws.String(store.Endpoint)
+		}
+	})
+
+	return &client{
+		apiClient: apiClient,
+		prefix:    store.Prefix,
+	}, nil
+}
+
+func (p *Provider) Validate(backend v1alpha1.SecretStoreSpec) error {
+	if backend.AWSSecretsManager == nil {
+		return errors.New("empty AWSSecretsManager config")
+	}
+	if backend.AWSSecretsManager.Region == "" {
+		return errors.New("empty .AWSSecretsManager.Region")
+	}
+
+	creds := backend.AWSSecretsManager.Credentials
+	switch creds.GetSource() {
+	case v1alpha1.AWSCredentialsSourceDefault:
+	case v1alpha1.AWSCredentialsSourceStatic:
+		if creds.AccessKeyID == "" {
+			return errors.New("empty .AWSSecretsManager.Credentials.AccessKeyID")
+		}
+		if creds.SecretAccessKey == "" {
+			return errors.New("empty .AWSSecretsManager.Credentials.SecretAccessKey")
+		}
+	case v1alpha1.AWSCredentialsSourceProfile:
+		if creds.Profile == "" {
+			return errors.New("empty .AWSSecretsManager.Credentials.Profile")
+		}
+	default:
+		return fmt.Errorf("unsupported .AWSSecretsManager.Credentials.Source %q", creds.Source)
+	}
+
+	return nil
+}
+
+func init() {
+	v1alpha1.Register(&Provider{}, &v1alpha1.SecretStoreSpec{
+		AWSSecretsManager: &v1alpha1.AWSSecretsManagerStore{},
+	})
+}
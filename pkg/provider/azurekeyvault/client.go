@@ -0,0 +1,357 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azurekeyvault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bank-vaults/secret-sync/pkg/apis/v1alpha1"
+)
+
+const (
+	apiVersion = "7.4"
+
+	// maxRecoverPolls limits how many times a recovered secret is checked before giving up.
+	maxRecoverPolls = 30
+)
+
+// secretNameRegexp defines allowed Key Vault secret names.
+var secretNameRegexp = regexp.MustCompile(`^[0-9a-zA-Z-]{1,127}$`)
+
+type client struct {
+	httpClient *http.Client
+	tokens     *tokenSource
+	vaultURL   string
+	separator  string
+
+	recoverInterval time.Duration // how often to check if a deleted secret was recovered
+}
+
+type secretBundle struct {
+	ID    string `json:"id,omitempty"`
+	Value string `json:"value,omitempty"`
+}
+
+type secretList struct {
+	Value    []secretBundle `json:"value"`
+	NextLink string         `json:"nextLink"`
+}
+
+func (c *client) GetSecret(ctx context.Context, key v1alpha1.SecretRef) ([]byte, error) {
+	name, err := c.nameForKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	secretPath := "/secrets/" + name
+	if key.Version != nil && *key.Version != "" {
+		secretPath += "/" + *key.Version
+	}
+
+	// Get secret from API
+	var secret secretBundle
+	if err := c.do(ctx, http.MethodGet, c.urlFor(secretPath), nil, &secret); err != nil {
+		return nil, fmt.Errorf("api get request failed: %w", err)
+	}
+
+	return []byte(secret.Value), nil
+}
+
+func (c *client) ListSecretKeys(ctx context.Context, query v1alpha1.SecretQuery) ([]v1alpha1.SecretRef, error) {
+	// Get name prefix to query
+	namePrefix := ""
+	if query.Path != nil {
+		if queryPath := strings.Trim(*query.Path, "/"); queryPath != "" {
+			namePrefix = strings.ReplaceAll(queryPath, "/", c.separator) + c.separator
+		}
+	}
+
+	// List API request, following pages
+	var result []v1alpha1.SecretRef
+	for nextLink := c.urlFor("/secrets"); nextLink != ""; {
+		var page secretList
+		if err := c.do(ctx, http.MethodGet, nextLink, nil, &page); err != nil {
+			return nil, fmt.Errorf("api list request failed: %w", err)
+		}
+
+		for _, secret := range page.Value {
+			name := path.Base(secret.ID)
+			if !strings.HasPrefix(name, namePrefix) {
+				continue
+			}
+
+			// Skip secrets in nested paths which are not queried
+			keyPath := strings.TrimPrefix(name, namePrefix)
+			if keyPath == "" || !query.IncludesDepth(strings.Count(keyPath, c.separator)) {
+				continue
+			}
+
+			// Skip secrets which do not map to keys, e.g. "db----password"
+			keyParts := strings.Split(keyPath, c.separator)
+			if slices.Contains(keyParts, "") {
+				continue
+			}
+			keyName := keyParts[len(keyParts)-1]
+
+			// Add key if it matches regexp query
+			if matches, _ := regexp.MatchString(query.Key.Regexp, keyName); matches {
+				result = append(result, v1alpha1.SecretRef{
+					Key: "/" + strings.ReplaceAll(name, c.separator, "/"),
+				})
+			}
+		}
+
+		// Only follow pages on the vault host, requests carry the access token
+		nextLink = page.NextLink
+		if nextLink != "" && !c.isVaultURL(nextLink) {
+			return nil, fmt.Errorf("api list returned next link %q outside of vault %q", nextLink, c.vaultURL)
+		}
+	}
+
+	return result, nil
+}
+
+func (c *client) SetSecret(ctx context.Context, key v1alpha1.SecretRef, value []byte) error {
+	name, err := c.nameForKey(key)
+	if err != nil {
+		return err
+	}
+
+	// Write secret to API, creates a new version
+	body, err := json.Marshal(secretBundle{Value: string(value)})
+	if err != nil {
+		return err
+	}
+
+	err = c.do(ctx, http.MethodPut, c.urlFor("/secrets/"+name), body, nil)
+	if isDeletedButRecoverable(err) {
+		// Deleted secrets keep their name until purged, recover to write a new version
+		if err := c.recoverSecret(ctx, name); err != nil {
+			return fmt.Errorf("api recover request failed: %w", err)
+		}
+		err = c.do(ctx, http.MethodPut, c.urlFor("/secrets/"+name), body, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("api set request failed: %w", err)
+	}
+
+	return nil
+}
+
+// recoverSecret recovers a deleted secret and waits until the recovery completes.
+func (c *client) recoverSecret(ctx context.Context, name string) error {
+	if err := c.do(ctx, http.MethodPost, c.urlFor("/deletedsecrets/"+name+"/recover"), nil, nil); err != nil {
+		return err
+	}
+
+	for range maxRecoverPolls {
+		err := c.do(ctx, http.MethodGet, c.urlFor("/secrets/"+name), nil, nil)
+		if !errors.Is(err, v1alpha1.ErrKeyNotFound) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(c.recoverInterval):
+		}
+	}
+
+	return fmt.Errorf("%w: secret %q was not recovered in time", v1alpha1.ErrTransient, name)
+}
+
+func (c *client) DeleteSecret(ctx context.Context, key v1alpha1.SecretRef) error {
+	name, err := c.nameForKey(key)
+	if err != nil {
+		return err
+	}
+
+	// Deletes all secret versions, recoverable if soft-delete is enabled on the vault.
+	// Deleted secrets are recovered by SetSecret if the key is synced again.
+	if err := c.do(ctx, http.MethodDelete, c.urlFor("/secrets/"+name), nil, nil); err != nil {
+		return fmt.Errorf("api delete request failed: %w", err)
+	}
+
+	return nil
+}
+
+// do sends an authorized request to Key Vault API and decodes the response into out (if not nil).
+func (c *client) do(ctx context.Context, method, reqURL string, body []byte, out interface{}) error {
+	token, err := c.tokens.Token(ctx)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return classifyError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return v1alpha1.ErrKeyNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return classifyError(newAPIError(resp))
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// urlFor returns API URL for a given path.
+func (c *client) urlFor(apiPath string) string {
+	return fmt.Sprintf("%s%s?api-version=%s", c.vaultURL, apiPath, url.QueryEscape(apiVersion))
+}
+
+// isVaultURL checks if reqURL points to the same scheme and host as the vault URL.
+func (c *client) isVaultURL(reqURL string) bool {
+	parsed, err := url.Parse(reqURL)
+	if err != nil {
+		return false
+	}
+
+	vaultURL, err := url.Parse(c.vaultURL)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(parsed.Scheme, vaultURL.Scheme) && strings.EqualFold(parsed.Host, vaultURL.Host)
+}
+
+// nameForKey returns secret name for a key, e.g. nameForKey("/path/to/key") returns "path--to--key".
+// Keys are rejected if their secret name does not map back to the same key,
+// e.g. "/db/db--password" would be listed as "/db/db/password".
+func (c *client) nameForKey(key v1alpha1.SecretRef) (string, error) {
+	keyParts := append(key.GetPath(), key.GetName())
+	name := strings.Join(keyParts, c.separator)
+	if !slices.Equal(strings.Split(name, c.separator), keyParts) {
+		return "", fmt.Errorf("key %q maps to ambiguous secret name %q, key segments must not contain separator %q", key.Key, name, c.separator)
+	}
+	if !secretNameRegexp.MatchString(name) {
+		return "", fmt.Errorf("key %q maps to invalid secret name %q", key.Key, name)
+	}
+
+	return name, nil
+}
+
+// apiError describes an unexpected Key Vault API response.
+type apiError struct {
+	StatusCode int
+	Code       string // most specific error code, if returned
+	Message    string
+}
+
+func (e *apiError) Error() string {
+	if e.Code != "" {
+		return fmt.Sprintf("unexpected status %d (%s): %s", e.StatusCode, e.Code, e.Message)
+	}
+
+	return fmt.Sprintf("unexpected status %d: %s", e.StatusCode, e.Message)
+}
+
+// newAPIError returns apiError for an unexpected response, decoding the error body if possible.
+func newAPIError(resp *http.Response) *apiError {
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	apiErr := &apiError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(respBody))}
+
+	var errBody struct {
+		Error struct {
+			Code       string `json:"code"`
+			Message    string `json:"message"`
+			InnerError struct {
+				Code string `json:"code"`
+			} `json:"innererror"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(respBody, &errBody); err == nil && errBody.Error.Code != "" {
+		apiErr.Code = errBody.Error.Code
+		if errBody.Error.InnerError.Code != "" {
+			apiErr.Code = errBody.Error.InnerError.Code
+		}
+		apiErr.Message = errBody.Error.Message
+	}
+
+	return apiErr
+}
+
+// isDeletedButRecoverable checks if a request failed because the secret was deleted, but not purged.
+func isDeletedButRecoverable(err error) bool {
+	var apiErr *apiError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusConflict && apiErr.Code == "ObjectIsDeletedButRecoverable"
+}
+
+// classifyError marks API errors caused by missing permissions or likely temporary failures.
+func classifyError(err error) error {
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.StatusCode == http.StatusUnauthorized, apiErr.StatusCode == http.StatusForbidden:
+			return fmt.Errorf("%w: %w", v1alpha1.ErrPermissionDenied, err)
+		case apiErr.StatusCode == http.StatusTooManyRequests, apiErr.StatusCode >= http.StatusInternalServerError:
+			return fmt.Errorf("%w: %w", v1alpha1.ErrTransient, err)
+		}
+
+		return err
+	}
+
+	if isNetworkFailure(err) {
+		return fmt.Errorf("%w: %w", v1alpha1.ErrTransient, err)
+	}
+
+	return err
+}
+
+// isNetworkFailure checks if a request failed due to a timeout or a connection error.
+// Cancelled requests and failures such as invalid certificates or unknown hosts are not temporary.
+func isNetworkFailure(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+
+	var opErr *net.OpError
+	var netErr net.Error
+	return errors.As(err, &opErr) || (errors.As(err, &netErr) && netErr.Timeout())
+}
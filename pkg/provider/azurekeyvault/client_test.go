@@ -0,0 +1,287 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azurekeyvault
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bank-vaults/secret-sync/pkg/apis/v1alpha1"
+)
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeServer(t, map[string]string{
+		"db--username":   "admin",
+		"db--password":   "secret",
+		"db--nested--id": "nested",
+		"db----invalid":  "invalid",
+	})
+
+	store := v1alpha1.SecretStoreSpec{
+		AzureKeyVault: &v1alpha1.AzureKeyVaultStore{
+			VaultURL:      server.URL,
+			TenantID:      "tenant",
+			ClientID:      "client",
+			ClientSecret:  "client-secret",
+			AuthorityHost: server.URL,
+		},
+	}
+	require.NoError(t, (&Provider{}).Validate(store))
+
+	// Single character separators are ambiguous in secret names
+	invalidStore := *store.AzureKeyVault
+	invalidStore.PathSeparator = "-"
+	assert.Error(t, (&Provider{}).Validate(v1alpha1.SecretStoreSpec{AzureKeyVault: &invalidStore}))
+
+	storeClient, err := (&Provider{}).NewClient(ctx, store)
+	require.NoError(t, err)
+
+	// Get
+	value, err := storeClient.GetSecret(ctx, v1alpha1.SecretRef{Key: "/db/username"})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("admin"), value)
+
+	_, err = storeClient.GetSecret(ctx, v1alpha1.SecretRef{Key: "/db/missing"})
+	assert.ErrorIs(t, err, v1alpha1.ErrKeyNotFound)
+
+	_, err = storeClient.GetSecret(ctx, v1alpha1.SecretRef{Key: "/db/invalid_name"})
+	assert.Error(t, err)
+
+	// Keys which would not be listed back as the same key are rejected
+	for _, key := range []string{"/db/db--password", "/db-/password"} {
+		err = storeClient.SetSecret(ctx, v1alpha1.SecretRef{Key: key}, []byte("secret"))
+		assert.ErrorContains(t, err, "ambiguous", key)
+	}
+	require.NoError(t, storeClient.SetSecret(ctx, v1alpha1.SecretRef{Key: "/db-main/db-password"}, []byte("secret")))
+	assert.Equal(t, "secret", server.secrets["db-main--db-password"])
+
+	// List
+	path := "/db/"
+	refs, err := storeClient.ListSecretKeys(ctx, v1alpha1.SecretQuery{Path: &path, Key: v1alpha1.Query{Regexp: ".*"}})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []v1alpha1.SecretRef{{Key: "/db/username"}, {Key: "/db/password"}}, refs)
+
+	// Set
+	require.NoError(t, storeClient.SetSecret(ctx, v1alpha1.SecretRef{Key: "/db/host"}, []byte("localhost")))
+	value, err = storeClient.GetSecret(ctx, v1alpha1.SecretRef{Key: "/db/host"})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("localhost"), value)
+
+	// Delete
+	require.NoError(t, storeClient.DeleteSecret(ctx, v1alpha1.SecretRef{Key: "/db/host"}))
+	_, err = storeClient.GetSecret(ctx, v1alpha1.SecretRef{Key: "/db/host"})
+	assert.ErrorIs(t, err, v1alpha1.ErrKeyNotFound)
+
+	err = storeClient.DeleteSecret(ctx, v1alpha1.SecretRef{Key: "/db/host"})
+	assert.ErrorIs(t, err, v1alpha1.ErrKeyNotFound)
+
+	// Deleted secret is recovered when set again
+	storeClient.(*client).recoverInterval = time.Millisecond
+	require.NoError(t, storeClient.SetSecret(ctx, v1alpha1.SecretRef{Key: "/db/host"}, []byte("remote")))
+	value, err = storeClient.GetSecret(ctx, v1alpha1.SecretRef{Key: "/db/host"})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("remote"), value)
+	assert.Empty(t, server.deleted)
+
+	// Token is requested once and reused
+	assert.Equal(t, 1, server.tokenRequests)
+}
+
+func TestListForeignNextLink(t *testing.T) {
+	ctx := context.Background()
+
+	// Requests to other hosts must not receive the access token
+	var foreignRequests int
+	foreign := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		foreignRequests++
+		writeJSON(w, http.StatusOK, secretList{})
+	}))
+	t.Cleanup(foreign.Close)
+
+	server := newFakeServer(t, map[string]string{"db--username": "admin"})
+	server.nextLink = foreign.URL + "/secrets?api-version=" + apiVersion
+
+	storeClient, err := (&Provider{}).NewClient(ctx, v1alpha1.SecretStoreSpec{
+		AzureKeyVault: &v1alpha1.AzureKeyVaultStore{
+			VaultURL:      server.URL,
+			TenantID:      "tenant",
+			ClientID:      "client",
+			ClientSecret:  "client-secret",
+			AuthorityHost: server.URL,
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = storeClient.ListSecretKeys(ctx, v1alpha1.SecretQuery{Key: v1alpha1.Query{Regexp: ".*"}})
+	assert.Error(t, err)
+	assert.Zero(t, foreignRequests)
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantErr error
+	}{
+		{name: "Unauthorized", err: &apiError{StatusCode: http.StatusUnauthorized}, wantErr: v1alpha1.ErrPermissionDenied},
+		{name: "Forbidden", err: &apiError{StatusCode: http.StatusForbidden}, wantErr: v1alpha1.ErrPermissionDenied},
+		{name: "Too many requests", err: &apiError{StatusCode: http.StatusTooManyRequests}, wantErr: v1alpha1.ErrTransient},
+		{name: "Unavailable", err: &apiError{StatusCode: http.StatusServiceUnavailable}, wantErr: v1alpha1.ErrTransient},
+		{name: "Bad request", err: &apiError{StatusCode: http.StatusBadRequest}},
+		{name: "Connection refused", err: &url.Error{Op: "Get", URL: "https://store", Err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}}, wantErr: v1alpha1.ErrTransient},
+		{name: "Timeout", err: &url.Error{Op: "Get", URL: "https://store", Err: os.ErrDeadlineExceeded}, wantErr: v1alpha1.ErrTransient},
+		{name: "Unknown host", err: &url.Error{Op: "Get", URL: "https://store", Err: &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "store", IsNotFound: true}}}},
+		{name: "Invalid certificate", err: &url.Error{Op: "Get", URL: "https://store", Err: &tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}}},
+		{name: "Cancelled", err: &url.Error{Op: "Get", URL: "https://store", Err: context.Canceled}},
+	}
+
+	for _, tt := range tests {
+		ttp := tt
+		t.Run(ttp.name, func(t *testing.T) {
+			err := classifyError(ttp.err)
+			assert.ErrorIs(t, err, ttp.err)
+			if ttp.wantErr == nil {
+				assert.False(t, errors.Is(err, v1alpha1.ErrPermissionDenied) || errors.Is(err, v1alpha1.ErrTransient))
+				return
+			}
+			assert.ErrorIs(t, err, ttp.wantErr)
+		})
+	}
+}
+
+type fakeServer struct {
+	*httptest.Server
+	mu            sync.Mutex
+	secrets       map[string]string
+	deleted       map[string]string // deleted secrets which can be recovered
+	recovering    map[string]bool   // recovered secrets not yet readable
+	nextLink      string            // returned for the first list page, if set
+	tokenRequests int
+}
+
+// newFakeServer creates a minimal token and Key Vault REST API stand-in.
+func newFakeServer(t *testing.T, secrets map[string]string) *fakeServer {
+	fake := &fakeServer{secrets: secrets, deleted: map[string]string{}, recovering: map[string]bool{}}
+	fake.Server = httptest.NewServer(http.HandlerFunc(fake.handle))
+	t.Cleanup(fake.Close)
+
+	return fake
+}
+
+func (s *fakeServer) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.URL.Path == "/tenant/oauth2/v2.0/token" {
+		_ = r.ParseForm()
+		if r.PostForm.Get("client_secret") != "client-secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		s.tokenRequests++
+		writeJSON(w, http.StatusOK, map[string]any{"access_token": "token", "expires_in": 3600})
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/secrets/")
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/secrets":
+		var items []secretBundle
+		for name := range s.secrets {
+			items = append(items, secretBundle{ID: s.URL + "/secrets/" + name})
+		}
+		sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+		writeJSON(w, http.StatusOK, secretList{Value: items, NextLink: s.nextLink})
+
+	case r.Method == http.MethodPost && strings.HasPrefix(r.URL.Path, "/deletedsecrets/"):
+		name = strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/deletedsecrets/"), "/recover")
+		value, ok := s.deleted[name]
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": map[string]string{"code": "SecretNotFound"}})
+			return
+		}
+		delete(s.deleted, name)
+		s.secrets[name] = value
+		s.recovering[name] = true
+		writeJSON(w, http.StatusOK, secretBundle{ID: s.URL + "/secrets/" + name})
+
+	case r.Method == http.MethodGet:
+		value, ok := s.secrets[name]
+		if s.recovering[name] {
+			// Recovery completes after the first check
+			delete(s.recovering, name)
+			ok = false
+		}
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": map[string]string{"code": "SecretNotFound"}})
+			return
+		}
+		writeJSON(w, http.StatusOK, secretBundle{ID: s.URL + r.URL.Path, Value: value})
+
+	case r.Method == http.MethodPut && s.deleted[name] != "":
+		writeJSON(w, http.StatusConflict, map[string]any{"error": map[string]any{
+			"code":       "Conflict",
+			"message":    "Secret is currently in a deleted but recoverable state",
+			"innererror": map[string]string{"code": "ObjectIsDeletedButRecoverable"},
+		}})
+
+	case r.Method == http.MethodPut:
+		var secret secretBundle
+		_ = json.NewDecoder(r.Body).Decode(&secret)
+		s.secrets[name] = secret.Value
+		writeJSON(w, http.StatusOK, secretBundle{ID: s.URL + r.URL.Path, Value: secret.Value})
+
+	case r.Method == http.MethodDelete:
+		if _, ok := s.secrets[name]; !ok {
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": map[string]string{"code": "SecretNotFound"}})
+			return
+		}
+		s.deleted[name] = s.secrets[name]
+		delete(s.secrets, name)
+		writeJSON(w, http.StatusOK, secretBundle{ID: s.URL + r.URL.Path})
+
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
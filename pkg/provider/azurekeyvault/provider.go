@@ -0,0 +1,81 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azurekeyvault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bank-vaults/secret-sync/pkg/apis/v1alpha1"
+)
+
+// pathSeparatorRegexp defines allowed path separators, they must be valid in secret names.
+// Separators have at least 2 characters, so that key segments can still contain single dashes.
+var pathSeparatorRegexp = regexp.MustCompile(`^[0-9a-zA-Z-]{2,}$`)
+
+type Provider struct{}
+
+func (p *Provider) NewClient(_ context.Context, backend v1alpha1.SecretStoreSpec) (v1alpha1.StoreClient, error) {
+	store := backend.AzureKeyVault
+	httpClient := &http.Client{Timeout: 30 * time.Second}
+
+	return &client{
+		httpClient: httpClient,
+		tokens: &tokenSource{
+			httpClient:   httpClient,
+			tokenURL:     fmt.Sprintf("%s/%s/oauth2/v2.0/token", strings.TrimSuffix(store.GetAuthorityHost(), "/"), store.TenantID),
+			clientID:     store.ClientID,
+			clientSecret: store.ClientSecret,
+			tokenPath:    store.TokenPath,
+		},
+		vaultURL:        strings.TrimSuffix(store.VaultURL, "/"),
+		separator:       store.GetPathSeparator(),
+		recoverInterval: time.Second,
+	}, nil
+}
+
+func (p *Provider) Validate(backend v1alpha1.SecretStoreSpec) error {
+	if backend.AzureKeyVault == nil {
+		return errors.New("empty AzureKeyVault config")
+	}
+	if backend.AzureKeyVault.VaultURL == "" {
+		return errors.New("empty .AzureKeyVault.VaultURL")
+	}
+	if backend.AzureKeyVault.TenantID == "" {
+		return errors.New("empty .AzureKeyVault.TenantID")
+	}
+	if backend.AzureKeyVault.ClientID == "" {
+		return errors.New("empty .AzureKeyVault.ClientID")
+	}
+	if (backend.AzureKeyVault.ClientSecret == "") == (backend.AzureKeyVault.TokenPath == "") {
+		return errors.New("exactly one of .AzureKeyVault.ClientSecret or .AzureKeyVault.TokenPath required")
+	}
+	if !pathSeparatorRegexp.MatchString(backend.AzureKeyVault.GetPathSeparator()) {
+		return errors.New("invalid .AzureKeyVault.PathSeparator, requires at least 2 alphanumerics or dashes")
+	}
+
+	return nil
+}
+
+func init() {
+	v1alpha1.Register(&Provider{}, &v1alpha1.SecretStoreSpec{
+		AzureKeyVault: &v1alpha1.AzureKeyVaultStore{},
+	})
+}
@@ -0,0 +1,152 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azurekeyvault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	keyVaultScope = "https://vault.azure.net/.default"
+
+	// tokenExpiryDelta defines how long before expiry the token is refreshed.
+	tokenExpiryDelta = time.Minute
+
+	// tokenRequestTimeout limits how long a shared token request can take.
+	tokenRequestTimeout = 30 * time.Second
+)
+
+// tokenSource obtains and caches access tokens using OAuth2 client credentials flow.
+type tokenSource struct {
+	httpClient *http.Client
+	tokenURL   string
+	clientID   string
+
+	// Only 1 is set
+	clientSecret string
+	tokenPath    string
+
+	refresh   singleflight.Group
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// Token returns a cached access token or requests a new one if expired.
+// Concurrent callers share a single token request, but each of them
+// stops waiting for it once its own ctx is done.
+func (s *tokenSource) Token(ctx context.Context) (string, error) {
+	if token, ok := s.cached(); ok {
+		return token, nil
+	}
+
+	result := s.refresh.DoChan("token", func() (interface{}, error) {
+		if token, ok := s.cached(); ok {
+			return token, nil
+		}
+
+		// Request is not tied to the caller which started it
+		reqCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), tokenRequestTimeout)
+		defer cancel()
+
+		return s.requestToken(reqCtx)
+	})
+
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case res := <-result:
+		if res.Err != nil {
+			return "", res.Err
+		}
+
+		return res.Val.(string), nil
+	}
+}
+
+// cached returns the cached access token if it is not about to expire.
+func (s *tokenSource) cached() (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && time.Now().Add(tokenExpiryDelta).Before(s.expiresAt) {
+		return s.token, true
+	}
+
+	return "", false
+}
+
+// requestToken requests a new access token and caches it.
+func (s *tokenSource) requestToken(ctx context.Context) (string, error) {
+	form := url.Values{
+		"grant_type": {"client_credentials"},
+		"client_id":  {s.clientID},
+		"scope":      {keyVaultScope},
+	}
+
+	if s.tokenPath != "" {
+		// Federated token file may be rotated, always read the latest one
+		assertion, err := os.ReadFile(s.tokenPath)
+		if err != nil {
+			return "", fmt.Errorf("failed to read token file: %w", err)
+		}
+
+		form.Set("client_assertion_type", "urn:ietf:params:oauth:client-assertion-type:jwt-bearer")
+		form.Set("client_assertion", strings.TrimSpace(string(assertion)))
+	} else {
+		form.Set("client_secret", s.clientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("token request failed: %w", classifyError(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token request failed: %w", classifyError(newAPIError(resp)))
+	}
+
+	var tokenResp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return "", fmt.Errorf("token request returned invalid data: %w", err)
+	}
+
+	s.mu.Lock()
+	s.token = tokenResp.AccessToken
+	s.expiresAt = time.Now().Add(time.Duration(tokenResp.ExpiresIn) * time.Second)
+	s.mu.Unlock()
+
+	return tokenResp.AccessToken, nil
+}
@@ -0,0 +1,106 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package azurekeyvault
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bank-vaults/secret-sync/pkg/apis/v1alpha1"
+)
+
+func TestTokenSourceConcurrent(t *testing.T) {
+	release := make(chan struct{})
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		<-release
+		writeJSON(w, http.StatusOK, map[string]any{"access_token": "token", "expires_in": 3600})
+	}))
+	t.Cleanup(server.Close)
+
+	tokens := &tokenSource{httpClient: server.Client(), tokenURL: server.URL, clientSecret: "client-secret"}
+
+	// Callers share a single token request
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			token, err := tokens.Token(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, "token", token)
+		}()
+	}
+
+	// Cancelled callers stop waiting without failing the shared request
+	require.Eventually(t, func() bool { return requests.Load() == 1 }, time.Second, time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := tokens.Token(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), requests.Load())
+
+	// Cached token is reused
+	token, err := tokens.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "token", token)
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestTokenSourceErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr error
+	}{
+		{name: "Unavailable", status: http.StatusServiceUnavailable, wantErr: v1alpha1.ErrTransient},
+		{name: "Throttled", status: http.StatusTooManyRequests, wantErr: v1alpha1.ErrTransient},
+		{name: "Unauthorized", status: http.StatusUnauthorized, wantErr: v1alpha1.ErrPermissionDenied},
+	}
+
+	for _, tt := range tests {
+		ttp := tt
+		t.Run(ttp.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				writeJSON(w, ttp.status, map[string]any{"error": "failed"})
+			}))
+			t.Cleanup(server.Close)
+
+			tokens := &tokenSource{httpClient: server.Client(), tokenURL: server.URL, clientSecret: "client-secret"}
+			_, err := tokens.Token(context.Background())
+			assert.ErrorIs(t, err, ttp.wantErr)
+		})
+	}
+
+	// Token requests which could not connect are retried
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+	tokens := &tokenSource{httpClient: server.Client(), tokenURL: server.URL, clientSecret: "client-secret"}
+	_, err := tokens.Token(context.Background())
+	assert.ErrorIs(t, err, v1alpha1.ErrTransient)
+}
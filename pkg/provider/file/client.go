@@ -16,7 +16,9 @@ package file
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -33,6 +35,10 @@ func (c *client) GetSecret(_ context.Context, key v1alpha1.SecretRef) ([]byte, e
 	// Read file
 	data, err := os.ReadFile(filepath.Join(c.dir, pathForKey(key)))
 	if err != nil {
+		if errors.Is(err, fs.ErrPermission) {
+			return nil, classifyError(err)
+		}
+
 		return nil, v1alpha1.ErrKeyNotFound
 	}
 
@@ -53,8 +59,18 @@ func (c *client) ListSecretKeys(_ context.Context, query v1alpha1.SecretQuery) (
 			return fmt.Errorf("list failed to walk dir: %w", err)
 		}
 
+		// Skip nested dirs which are not queried
+		relativePath, err := filepath.Rel(queryDir, path)
+		if err != nil {
+			return fmt.Errorf("list failed to resolve path: %w", err)
+		}
+		depth := strings.Count(relativePath, string(os.PathSeparator))
+		if entry != nil && entry.IsDir() && relativePath != "." && !query.IncludesDepth(depth+1) {
+			return filepath.SkipDir
+		}
+
 		// Only add files
-		if entry != nil && entry.Type().IsRegular() {
+		if entry != nil && entry.Type().IsRegular() && query.IncludesDepth(depth) {
 			// Extract secret key from the relative OS system path
 			relativePath := strings.ReplaceAll(path, c.dir+string(os.PathSeparator), "")
 			key := strings.ReplaceAll(relativePath, string(os.PathSeparator), "/")
@@ -83,12 +99,25 @@ func (c *client) SetSecret(_ context.Context, key v1alpha1.SecretRef, value []by
 
 	parentDir := filepath.Dir(fpath)
 	if err := os.MkdirAll(parentDir, os.ModePerm); err != nil {
-		return fmt.Errorf("set failed to create dir %s: %w", parentDir, err)
+		return fmt.Errorf("set failed to create dir %s: %w", parentDir, classifyError(err))
 	}
 
 	// Write file
 	if err := os.WriteFile(fpath, value, 0o600); err != nil {
-		return fmt.Errorf("set failed to write file %s: %w", fpath, err)
+		return fmt.Errorf("set failed to write file %s: %w", fpath, classifyError(err))
+	}
+
+	return nil
+}
+
+func (c *client) DeleteSecret(_ context.Context, key v1alpha1.SecretRef) error {
+	fpath := filepath.Join(c.dir, pathForKey(key))
+	if err := os.Remove(fpath); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return v1alpha1.ErrKeyNotFound
+		}
+
+		return fmt.Errorf("delete failed to remove file %s: %w", fpath, classifyError(err))
 	}
 
 	return nil
@@ -97,3 +126,12 @@ func (c *client) SetSecret(_ context.Context, key v1alpha1.SecretRef, value []by
 func pathForKey(key v1alpha1.SecretRef) string {
 	return filepath.Join(append(key.GetPath(), key.GetName())...)
 }
+
+// classifyError marks errors caused by missing file permissions.
+func classifyError(err error) error {
+	if errors.Is(err, fs.ErrPermission) {
+		return fmt.Errorf("%w: %w", v1alpha1.ErrPermissionDenied, err)
+	}
+
+	return err
+}
@@ -0,0 +1,110 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bank-vaults/secret-sync/pkg/apis/v1alpha1"
+)
+
+func TestClientListSecretKeys(t *testing.T) {
+	ctx := context.Background()
+	c := &client{dir: t.TempDir()}
+	for _, key := range []string{"/app/db", "/app/api", "/app/nested/token", "/app/nested/deep/key", "/other"} {
+		require.NoError(t, c.SetSecret(ctx, v1alpha1.SecretRef{Key: key}, []byte("value")))
+	}
+
+	tests := []struct {
+		name  string
+		query v1alpha1.SecretQuery
+		want  []v1alpha1.SecretRef
+	}{
+		{
+			name:  "Non-recursive by default",
+			query: v1alpha1.SecretQuery{Path: ptr("/app"), Key: v1alpha1.Query{Regexp: ".*"}},
+			want:  []v1alpha1.SecretRef{{Key: "/app/api"}, {Key: "/app/db"}},
+		},
+		{
+			name:  "Max depth without recursive",
+			query: v1alpha1.SecretQuery{Path: ptr("/app"), Key: v1alpha1.Query{Regexp: ".*"}, MaxDepth: 1},
+			want:  []v1alpha1.SecretRef{{Key: "/app/api"}, {Key: "/app/db"}},
+		},
+		{
+			name:  "Non-recursive",
+			query: v1alpha1.SecretQuery{Path: ptr("/app"), Key: v1alpha1.Query{Regexp: ".*"}, Recursive: ptr(false)},
+			want:  []v1alpha1.SecretRef{{Key: "/app/api"}, {Key: "/app/db"}},
+		},
+		{
+			name:  "Recursive",
+			query: v1alpha1.SecretQuery{Path: ptr("/app"), Key: v1alpha1.Query{Regexp: ".*"}, Recursive: ptr(true)},
+			want:  []v1alpha1.SecretRef{{Key: "/app/api"}, {Key: "/app/db"}, {Key: "/app/nested/deep/key"}, {Key: "/app/nested/token"}},
+		},
+		{
+			name:  "Recursive with max depth",
+			query: v1alpha1.SecretQuery{Path: ptr("/app"), Key: v1alpha1.Query{Regexp: ".*"}, Recursive: ptr(true), MaxDepth: 1},
+			want:  []v1alpha1.SecretRef{{Key: "/app/api"}, {Key: "/app/db"}, {Key: "/app/nested/token"}},
+		},
+		{
+			name:  "Recursive with key name query",
+			query: v1alpha1.SecretQuery{Key: v1alpha1.Query{Regexp: "^(key|other)$"}, Recursive: ptr(true)},
+			want:  []v1alpha1.SecretRef{{Key: "/app/nested/deep/key"}, {Key: "/other"}},
+		},
+	}
+
+	for _, tt := range tests {
+		ttp := tt
+		t.Run(ttp.name, func(t *testing.T) {
+			refs, err := c.ListSecretKeys(ctx, ttp.query)
+			require.NoError(t, err)
+			assert.Equal(t, ttp.want, refs)
+		})
+	}
+}
+
+func TestClientDeleteSecret(t *testing.T) {
+	ctx := context.Background()
+	c := &client{dir: t.TempDir()}
+	require.NoError(t, c.SetSecret(ctx, v1alpha1.SecretRef{Key: "/app/db"}, []byte("value")))
+
+	require.NoError(t, c.DeleteSecret(ctx, v1alpha1.SecretRef{Key: "/app/db"}))
+	_, err := c.GetSecret(ctx, v1alpha1.SecretRef{Key: "/app/db"})
+	assert.ErrorIs(t, err, v1alpha1.ErrKeyNotFound)
+
+	err = c.DeleteSecret(ctx, v1alpha1.SecretRef{Key: "/app/db"})
+	assert.ErrorIs(t, err, v1alpha1.ErrKeyNotFound)
+}
+
+func TestClientKeyWithHash(t *testing.T) {
+	ctx := context.Background()
+	c := &client{dir: t.TempDir()}
+
+	// Keys are not parsed for field selectors
+	require.NoError(t, c.SetSecret(ctx, v1alpha1.SecretRef{Key: "/app/db#password"}, []byte("value")))
+	_, err := c.GetSecret(ctx, v1alpha1.SecretRef{Key: "/app/db"})
+	assert.ErrorIs(t, err, v1alpha1.ErrKeyNotFound)
+
+	value, err := c.GetSecret(ctx, v1alpha1.SecretRef{Key: "/app/db#password"})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), value)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,259 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcpsecretmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/secretmanager/v1"
+
+	"github.com/bank-vaults/secret-sync/pkg/apis/v1alpha1"
+)
+
+// keySeparator replaces "/" in keys since it is not allowed in secret IDs.
+const keySeparator = "--"
+
+// secretIDRegexp defines allowed Secret Manager secret IDs.
+var secretIDRegexp = regexp.MustCompile(`^[0-9a-zA-Z_-]{1,255}$`)
+
+type client struct {
+	apiClient *secretmanager.Service
+	projectID string
+}
+
+func (c *client) GetSecret(ctx context.Context, key v1alpha1.SecretRef) ([]byte, error) {
+	version := "latest"
+	if key.Version != nil && *key.Version != "" {
+		version = *key.Version
+	}
+
+	secretID, err := idForKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.accessVersion(ctx, secretID, version)
+}
+
+func (c *client) ListSecretKeys(ctx context.Context, query v1alpha1.SecretQuery) ([]v1alpha1.SecretRef, error) {
+	// Get secret ID prefix to query
+	idPrefix := ""
+	if query.Path != nil {
+		if queryPath := strings.Trim(*query.Path, "/"); queryPath != "" {
+			idPrefix = strings.ReplaceAll(queryPath, "/", keySeparator) + keySeparator
+		}
+	}
+
+	// List API request
+	var result []v1alpha1.SecretRef
+	err := c.apiClient.Projects.Secrets.List(c.projectPath()).Pages(ctx, func(page *secretmanager.ListSecretsResponse) error {
+		for _, secret := range page.Secrets {
+			secretID := path.Base(secret.Name)
+			if !strings.HasPrefix(secretID, idPrefix) {
+				continue
+			}
+
+			// Skip secrets in nested paths which are not queried
+			keyPath := strings.TrimPrefix(secretID, idPrefix)
+			if keyPath == "" || !query.IncludesDepth(strings.Count(keyPath, keySeparator)) {
+				continue
+			}
+
+			// Skip secrets which do not map to keys, e.g. "db----password"
+			keyParts := strings.Split(keyPath, keySeparator)
+			if slices.Contains(keyParts, "") {
+				continue
+			}
+			keyName := keyParts[len(keyParts)-1]
+
+			// Add key if it matches regexp query
+			if matches, _ := regexp.MatchString(query.Key.Regexp, keyName); matches {
+				result = append(result, v1alpha1.SecretRef{
+					Key: "/" + strings.ReplaceAll(secretID, keySeparator, "/"),
+				})
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("api list request failed: %w", classifyError(err))
+	}
+
+	return result, nil
+}
+
+func (c *client) SetSecret(ctx context.Context, key v1alpha1.SecretRef, value []byte) error {
+	secretID, err := idForKey(key)
+	if err != nil {
+		return err
+	}
+
+	// Only add a new version if the payload differs from the latest one
+	latest, err := c.accessVersion(ctx, secretID, "latest")
+	switch {
+	case err == nil && bytes.Equal(latest, value):
+		return nil
+
+	case errors.Is(err, v1alpha1.ErrKeyNotFound):
+		// Create secret if it does not exist
+		_, err = c.apiClient.Projects.Secrets.Create(c.projectPath(), &secretmanager.Secret{
+			Replication: &secretmanager.Replication{
+				Automatic: &secretmanager.Automatic{},
+			},
+		}).SecretId(secretID).Context(ctx).Do()
+		if err != nil && !hasStatus(err, http.StatusConflict) {
+			return fmt.Errorf("api create request failed: %w", classifyError(err))
+		}
+
+	case err != nil:
+		// Do not write if the current value could not be read
+		return err
+	}
+
+	// Add new version
+	_, err = c.apiClient.Projects.Secrets.AddVersion(c.secretPath(secretID), &secretmanager.AddSecretVersionRequest{
+		Payload: &secretmanager.SecretPayload{
+			Data: base64.StdEncoding.EncodeToString(value),
+		},
+	}).Context(ctx).Do()
+	if err != nil {
+		return fmt.Errorf("api set request failed: %w", classifyError(err))
+	}
+
+	return nil
+}
+
+func (c *client) DeleteSecret(ctx context.Context, key v1alpha1.SecretRef) error {
+	secretID, err := idForKey(key)
+	if err != nil {
+		return err
+	}
+
+	// Deletes the secret with all its versions
+	_, err = c.apiClient.Projects.Secrets.Delete(c.secretPath(secretID)).Context(ctx).Do()
+	if err != nil {
+		if hasStatus(err, http.StatusNotFound) {
+			return v1alpha1.ErrKeyNotFound
+		}
+
+		return fmt.Errorf("api delete request failed: %w", classifyError(err))
+	}
+
+	return nil
+}
+
+// accessVersion returns the payload of a specific secret version.
+func (c *client) accessVersion(ctx context.Context, secretID, version string) ([]byte, error) {
+	response, err := c.apiClient.Projects.Secrets.Versions.Access(
+		fmt.Sprintf("%s/versions/%s", c.secretPath(secretID), version),
+	).Context(ctx).Do()
+	if err != nil {
+		if hasStatus(err, http.StatusNotFound) {
+			return nil, v1alpha1.ErrKeyNotFound
+		}
+
+		return nil, fmt.Errorf("api get request failed: %w", classifyError(err))
+	}
+
+	if response.Payload == nil {
+		return nil, errors.New("api get returned empty data")
+	}
+
+	data, err := base64.StdEncoding.DecodeString(response.Payload.Data)
+	if err != nil {
+		return nil, fmt.Errorf("api get returned invalid data: %w", err)
+	}
+
+	return data, nil
+}
+
+func (c *client) projectPath() string {
+	return "projects/" + c.projectID
+}
+
+func (c *client) secretPath(secretID string) string {
+	return fmt.Sprintf("%s/secrets/%s", c.projectPath(), secretID)
+}
+
+// idForKey returns secret ID for a key, e.g. idForKey("/path/to/key") returns "path--to--key".
+// Keys are rejected if their secret ID does not map back to the same key,
+// e.g. "/db/db--password" would be listed as "/db/db/password".
+func idForKey(key v1alpha1.SecretRef) (string, error) {
+	keyParts := append(key.GetPath(), key.GetName())
+	secretID := strings.Join(keyParts, keySeparator)
+	if !slices.Equal(strings.Split(secretID, keySeparator), keyParts) {
+		return "", fmt.Errorf("key %q maps to ambiguous secret ID %q, key segments must not contain %q", key.Key, secretID, keySeparator)
+	}
+	if !secretIDRegexp.MatchString(secretID) {
+		return "", fmt.Errorf("key %q maps to invalid secret ID %q", key.Key, secretID)
+	}
+
+	return secretID, nil
+}
+
+func hasStatus(err error, code int) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
+
+// classifyError marks API errors caused by missing permissions or likely temporary failures.
+func classifyError(err error) error {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.Code == http.StatusForbidden:
+			return fmt.Errorf("%w: %w", v1alpha1.ErrPermissionDenied, err)
+		case apiErr.Code == http.StatusTooManyRequests, apiErr.Code >= http.StatusInternalServerError:
+			return fmt.Errorf("%w: %w", v1alpha1.ErrTransient, err)
+		}
+
+		return err
+	}
+
+	if isNetworkFailure(err) {
+		return fmt.Errorf("%w: %w", v1alpha1.ErrTransient, err)
+	}
+
+	return err
+}
+
+// isNetworkFailure checks if a request failed due to a timeout or a connection error.
+// Cancelled requests and failures such as invalid certificates or unknown hosts are not temporary.
+func isNetworkFailure(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+
+	var opErr *net.OpError
+	var netErr net.Error
+	return errors.As(err, &opErr) || (errors.As(err, &netErr) && netErr.Timeout())
+}
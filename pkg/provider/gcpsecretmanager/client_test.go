@@ -0,0 +1,260 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcpsecretmanager
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/secretmanager/v1"
+
+	"github.com/bank-vaults/secret-sync/pkg/apis/v1alpha1"
+)
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeServer(t, map[string][]string{
+		"db--username":   {"admin"},
+		"db--password":   {"old", "secret"},
+		"db--nested--id": {"nested"},
+		"db----invalid":  {"invalid"},
+	})
+
+	c := newTestClient(t, server)
+
+	// Get
+	value, err := c.GetSecret(ctx, v1alpha1.SecretRef{Key: "/db/password"})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("secret"), value)
+
+	version := "1"
+	value, err = c.GetSecret(ctx, v1alpha1.SecretRef{Key: "/db/password", Version: &version})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("old"), value)
+
+	_, err = c.GetSecret(ctx, v1alpha1.SecretRef{Key: "/db/missing"})
+	assert.ErrorIs(t, err, v1alpha1.ErrKeyNotFound)
+
+	// Keys which do not map to valid secret IDs are rejected before calling the API
+	for _, key := range []string{"/db/db--password", "/db-/password", "/db/pass.word"} {
+		err = c.SetSecret(ctx, v1alpha1.SecretRef{Key: key}, []byte("secret"))
+		assert.ErrorContains(t, err, "secret ID", key)
+	}
+	require.NoError(t, c.SetSecret(ctx, v1alpha1.SecretRef{Key: "/db_main/db-password"}, []byte("secret")))
+	assert.Equal(t, []string{"secret"}, server.versions["db_main--db-password"])
+
+	// List
+	path := "/db"
+	refs, err := c.ListSecretKeys(ctx, v1alpha1.SecretQuery{Path: &path, Key: v1alpha1.Query{Regexp: ".*"}})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []v1alpha1.SecretRef{{Key: "/db/username"}, {Key: "/db/password"}}, refs)
+
+	// Set
+	require.NoError(t, c.SetSecret(ctx, v1alpha1.SecretRef{Key: "/db/password"}, []byte("secret")))
+	assert.Len(t, server.versions["db--password"], 2, "unchanged value should not add a version")
+
+	require.NoError(t, c.SetSecret(ctx, v1alpha1.SecretRef{Key: "/db/password"}, []byte("changed")))
+	assert.Len(t, server.versions["db--password"], 3)
+
+	require.NoError(t, c.SetSecret(ctx, v1alpha1.SecretRef{Key: "/db/host"}, []byte("localhost")))
+	value, err = c.GetSecret(ctx, v1alpha1.SecretRef{Key: "/db/host"})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("localhost"), value)
+
+	// Delete
+	require.NoError(t, c.DeleteSecret(ctx, v1alpha1.SecretRef{Key: "/db/host"}))
+	assert.NotContains(t, server.versions, "db--host")
+
+	err = c.DeleteSecret(ctx, v1alpha1.SecretRef{Key: "/db/host"})
+	assert.ErrorIs(t, err, v1alpha1.ErrKeyNotFound)
+}
+
+// newTestClient creates a client for the fake server through the provider, without credentials.
+func newTestClient(t *testing.T, server *fakeServer) v1alpha1.StoreClient {
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", filepath.Join(t.TempDir(), "missing.json"))
+
+	store := v1alpha1.SecretStoreSpec{
+		GCPSecretManager: &v1alpha1.GCPSecretManagerStore{ProjectID: "test", Endpoint: server.URL + "/"},
+	}
+	require.NoError(t, (&Provider{}).Validate(store))
+
+	storeClient, err := (&Provider{}).NewClient(context.Background(), store)
+	require.NoError(t, err)
+
+	return storeClient
+}
+
+func TestSetSecretReadError(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeServer(t, map[string][]string{"db--password": {"secret"}})
+	server.accessStatus = http.StatusForbidden
+
+	c := newTestClient(t, server)
+
+	// Only missing secrets are created, other read errors are returned without writing
+	err := c.SetSecret(ctx, v1alpha1.SecretRef{Key: "/db/password"}, []byte("changed"))
+	assert.ErrorIs(t, err, v1alpha1.ErrPermissionDenied)
+	assert.Equal(t, []string{"secret"}, server.versions["db--password"])
+
+	err = c.SetSecret(ctx, v1alpha1.SecretRef{Key: "/db/host"}, []byte("localhost"))
+	assert.ErrorIs(t, err, v1alpha1.ErrPermissionDenied)
+	assert.NotContains(t, server.versions, "db--host")
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantErr error
+	}{
+		{name: "Forbidden", err: &googleapi.Error{Code: http.StatusForbidden}, wantErr: v1alpha1.ErrPermissionDenied},
+		{name: "Too many requests", err: &googleapi.Error{Code: http.StatusTooManyRequests}, wantErr: v1alpha1.ErrTransient},
+		{name: "Unavailable", err: &googleapi.Error{Code: http.StatusServiceUnavailable}, wantErr: v1alpha1.ErrTransient},
+		{name: "Bad request", err: &googleapi.Error{Code: http.StatusBadRequest}},
+		{name: "Connection refused", err: &url.Error{Op: "Get", URL: "https://store", Err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}}, wantErr: v1alpha1.ErrTransient},
+		{name: "Timeout", err: &url.Error{Op: "Get", URL: "https://store", Err: os.ErrDeadlineExceeded}, wantErr: v1alpha1.ErrTransient},
+		{name: "Unknown host", err: &url.Error{Op: "Get", URL: "https://store", Err: &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "store", IsNotFound: true}}}},
+		{name: "Invalid certificate", err: &url.Error{Op: "Get", URL: "https://store", Err: &tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}}},
+		{name: "Cancelled", err: &url.Error{Op: "Get", URL: "https://store", Err: context.Canceled}},
+	}
+
+	for _, tt := range tests {
+		ttp := tt
+		t.Run(ttp.name, func(t *testing.T) {
+			err := classifyError(ttp.err)
+			assert.ErrorIs(t, err, ttp.err)
+			if ttp.wantErr == nil {
+				assert.False(t, errors.Is(err, v1alpha1.ErrPermissionDenied) || errors.Is(err, v1alpha1.ErrTransient))
+				return
+			}
+			assert.ErrorIs(t, err, ttp.wantErr)
+		})
+	}
+}
+
+type fakeServer struct {
+	*httptest.Server
+	mu           sync.Mutex
+	versions     map[string][]string
+	accessStatus int // if set, access requests fail with this status
+}
+
+// newFakeServer creates a minimal Secret Manager REST API stand-in.
+func newFakeServer(t *testing.T, versions map[string][]string) *fakeServer {
+	fake := &fakeServer{versions: versions}
+	fake.Server = httptest.NewServer(http.HandlerFunc(fake.handle))
+	t.Cleanup(fake.Close)
+
+	return fake
+}
+
+func (s *fakeServer) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	const projectPath = "/v1/projects/test/secrets"
+	resource := strings.TrimPrefix(r.URL.Path, projectPath)
+
+	switch {
+	// List secrets
+	case r.Method == http.MethodGet && resource == "":
+		var secrets []map[string]string
+		for id := range s.versions {
+			secrets = append(secrets, map[string]string{"name": "projects/test/secrets/" + id})
+		}
+		sort.Slice(secrets, func(i, j int) bool { return secrets[i]["name"] < secrets[j]["name"] })
+		writeJSON(w, http.StatusOK, map[string]any{"secrets": secrets})
+
+	// Create secret
+	case r.Method == http.MethodPost && resource == "":
+		id := r.URL.Query().Get("secretId")
+		if _, ok := s.versions[id]; ok {
+			writeJSON(w, http.StatusConflict, map[string]any{"error": map[string]any{"code": http.StatusConflict}})
+			return
+		}
+		s.versions[id] = nil
+		writeJSON(w, http.StatusOK, map[string]string{"name": "projects/test/secrets/" + id})
+
+	// Add version
+	case r.Method == http.MethodPost && strings.HasSuffix(resource, ":addVersion"):
+		id := strings.TrimSuffix(strings.TrimPrefix(resource, "/"), ":addVersion")
+		var req secretmanager.AddSecretVersionRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		data, _ := base64.StdEncoding.DecodeString(req.Payload.Data)
+		s.versions[id] = append(s.versions[id], string(data))
+		writeJSON(w, http.StatusOK, map[string]string{
+			"name": fmt.Sprintf("projects/test/secrets/%s/versions/%d", id, len(s.versions[id])),
+		})
+
+	// Access version
+	case r.Method == http.MethodGet && strings.HasSuffix(resource, ":access"):
+		if s.accessStatus != 0 {
+			writeJSON(w, s.accessStatus, map[string]any{"error": map[string]any{"code": s.accessStatus}})
+			return
+		}
+		id, version, _ := strings.Cut(strings.TrimSuffix(strings.TrimPrefix(resource, "/"), ":access"), "/versions/")
+		versions := s.versions[id]
+		index := len(versions)
+		if version != "latest" {
+			index, _ = strconv.Atoi(version)
+		}
+		if index < 1 || index > len(versions) {
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": map[string]any{"code": http.StatusNotFound}})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"payload": map[string]string{"data": base64.StdEncoding.EncodeToString([]byte(versions[index-1]))},
+		})
+
+	// Delete secret
+	case r.Method == http.MethodDelete:
+		id := strings.TrimPrefix(resource, "/")
+		if _, ok := s.versions[id]; !ok {
+			writeJSON(w, http.StatusNotFound, map[string]any{"error": map[string]any{"code": http.StatusNotFound}})
+			return
+		}
+		delete(s.versions, id)
+		writeJSON(w, http.StatusOK, map[string]any{})
+
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,68 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gcpsecretmanager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/api/option"
+	"google.golang.org/api/secretmanager/v1"
+
+	"github.com/bank-vaults/secret-sync/pkg/apis/v1alpha1"
+)
+
+type Provider struct{}
+
+func (p *Provider) NewClient(ctx context.Context, backend v1alpha1.SecretStoreSpec) (v1alpha1.StoreClient, error) {
+	var opts []option.ClientOption
+	if backend.GCPSecretManager.Endpoint != "" {
+		opts = append(opts, option.WithEndpoint(backend.GCPSecretManager.Endpoint))
+	}
+	if backend.GCPSecretManager.CredentialsPath != "" {
+		opts = append(opts, option.WithAuthCredentialsFile(option.ServiceAccount, backend.GCPSecretManager.CredentialsPath))
+	} else if backend.GCPSecretManager.Endpoint != "" {
+		// Endpoint overrides such as emulators do not require credentials
+		opts = append(opts, option.WithoutAuthentication())
+	}
+
+	apiClient, err := secretmanager.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create secret manager client: %w", err)
+	}
+
+	return &client{
+		apiClient: apiClient,
+		projectID: backend.GCPSecretManager.ProjectID,
+	}, nil
+}
+
+func (p *Provider) Validate(backend v1alpha1.SecretStoreSpec) error {
+	if backend.GCPSecretManager == nil {
+		return errors.New("empty GCPSecretManager config")
+	}
+	if backend.GCPSecretManager.ProjectID == "" {
+		return errors.New("empty .GCPSecretManager.ProjectID")
+	}
+
+	return nil
+}
+
+func init() {
+	v1alpha1.Register(&Provider{}, &v1alpha1.SecretStoreSpec{
+		GCPSecretManager: &v1alpha1.GCPSecretManagerStore{},
+	})
+}
@@ -0,0 +1,230 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/bank-vaults/secret-sync/pkg/apis/v1alpha1"
+)
+
+type client struct {
+	apiClient kubernetes.Interface
+}
+
+func (c *client) GetSecret(ctx context.Context, key v1alpha1.SecretRef) ([]byte, error) {
+	namespace, name, err := secretForKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get secret from API
+	secret, err := c.apiClient.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, v1alpha1.ErrKeyNotFound
+		}
+
+		return nil, fmt.Errorf("api get request failed: %w", classifyError(err))
+	}
+
+	// Extract key data
+	data, ok := secret.Data[key.GetName()]
+	if !ok {
+		return nil, v1alpha1.ErrKeyNotFound
+	}
+
+	return data, nil
+}
+
+func (c *client) ListSecretKeys(ctx context.Context, query v1alpha1.SecretQuery) ([]v1alpha1.SecretRef, error) {
+	// Get namespace and (optional) secret name to query.
+	// Empty path queries secrets in all namespaces.
+	var namespace, name string
+	if query.Path != nil {
+		parts := strings.Split(strings.Trim(*query.Path, "/"), "/")
+		switch {
+		case len(parts) > 2:
+			return nil, fmt.Errorf("invalid query path %q, expected /<namespace>[/<secret-name>]", *query.Path)
+		case len(parts) == 2:
+			namespace, name = parts[0], parts[1]
+		default:
+			namespace = parts[0]
+		}
+	}
+
+	// Get secrets from API
+	var secrets []corev1.Secret
+	if name != "" {
+		secret, err := c.apiClient.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return nil, v1alpha1.ErrKeyNotFound
+			}
+
+			return nil, fmt.Errorf("api get request failed: %w", classifyError(err))
+		}
+
+		secrets = append(secrets, *secret)
+	} else {
+		secretList, err := c.apiClient.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("api list request failed: %w", classifyError(err))
+		}
+
+		secrets = secretList.Items
+	}
+
+	// Add all secret data keys that match the query
+	var result []v1alpha1.SecretRef
+	for _, secret := range secrets {
+		dataKeys := make([]string, 0, len(secret.Data))
+		for dataKey := range secret.Data {
+			dataKeys = append(dataKeys, dataKey)
+		}
+		sort.Strings(dataKeys)
+
+		for _, dataKey := range dataKeys {
+			if matches, _ := regexp.MatchString(query.Key.Regexp, dataKey); matches {
+				result = append(result, v1alpha1.SecretRef{
+					Key: fmt.Sprintf("/%s/%s/%s", secret.Namespace, secret.Name, dataKey),
+				})
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func (c *client) SetSecret(ctx context.Context, key v1alpha1.SecretRef, value []byte) error {
+	namespace, name, err := secretForKey(key)
+	if err != nil {
+		return err
+	}
+
+	// Multiple keys can be written to the same secret concurrently,
+	// so retry updates which failed due to stale resource version.
+	secrets := c.apiClient.CoreV1().Secrets(namespace)
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secret, err := secrets.Get(ctx, name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			_, err = secrets.Create(ctx, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: namespace,
+				},
+				Type: corev1.SecretTypeOpaque,
+				Data: map[string][]byte{
+					key.GetName(): value,
+				},
+			}, metav1.CreateOptions{})
+			if apierrors.IsAlreadyExists(err) {
+				// Created in the meantime, retry as update
+				return apierrors.NewConflict(corev1.Resource("secrets"), name, err)
+			}
+
+			return err
+		}
+		if err != nil {
+			return err
+		}
+
+		if secret.Data == nil {
+			secret.Data = map[string][]byte{}
+		}
+		secret.Data[key.GetName()] = value
+
+		_, err = secrets.Update(ctx, secret, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("api set request failed: %w", classifyError(err))
+	}
+
+	return nil
+}
+
+func (c *client) DeleteSecret(ctx context.Context, key v1alpha1.SecretRef) error {
+	namespace, name, err := secretForKey(key)
+	if err != nil {
+		return err
+	}
+
+	// Remove data key from secret, or the whole secret if no other data keys remain
+	secrets := c.apiClient.CoreV1().Secrets(namespace)
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secret, err := secrets.Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		if _, ok := secret.Data[key.GetName()]; !ok {
+			return v1alpha1.ErrKeyNotFound
+		}
+
+		if len(secret.Data) == 1 {
+			return secrets.Delete(ctx, name, metav1.DeleteOptions{
+				Preconditions: &metav1.Preconditions{ResourceVersion: &secret.ResourceVersion},
+			})
+		}
+
+		delete(secret.Data, key.GetName())
+		_, err = secrets.Update(ctx, secret, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, v1alpha1.ErrKeyNotFound) || apierrors.IsNotFound(err) {
+			return v1alpha1.ErrKeyNotFound
+		}
+
+		return fmt.Errorf("api delete request failed: %w", classifyError(err))
+	}
+
+	return nil
+}
+
+// secretForKey returns namespace and secret name for a key given as "/<namespace>/<secret-name>/<data-key>".
+func secretForKey(key v1alpha1.SecretRef) (string, string, error) {
+	path := key.GetPath()
+	if len(path) != 2 || path[0] == "" || path[1] == "" || key.GetName() == "" {
+		return "", "", fmt.Errorf("invalid key %q, expected /<namespace>/<secret-name>/<data-key>", key.Key)
+	}
+
+	return path[0], path[1], nil
+}
+
+// classifyError marks API errors caused by missing permissions or likely temporary failures.
+func classifyError(err error) error {
+	switch {
+	case apierrors.IsForbidden(err), apierrors.IsUnauthorized(err):
+		return fmt.Errorf("%w: %w", v1alpha1.ErrPermissionDenied, err)
+	case apierrors.IsTooManyRequests(err), apierrors.IsServerTimeout(err), apierrors.IsTimeout(err),
+		apierrors.IsServiceUnavailable(err), apierrors.IsInternalError(err):
+		return fmt.Errorf("%w: %w", v1alpha1.ErrTransient, err)
+	}
+
+	return err
+}
@@ -0,0 +1,125 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/bank-vaults/secret-sync/pkg/apis/v1alpha1"
+)
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	c := &client{
+		apiClient: fake.NewClientset(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"},
+			Data: map[string][]byte{
+				"username": []byte("admin"),
+				"password": []byte("secret"),
+			},
+		}),
+	}
+
+	// Get existing
+	value, err := c.GetSecret(ctx, v1alpha1.SecretRef{Key: "/default/db/username"})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("admin"), value)
+
+	// Get missing
+	_, err = c.GetSecret(ctx, v1alpha1.SecretRef{Key: "/default/db/missing"})
+	assert.ErrorIs(t, err, v1alpha1.ErrKeyNotFound)
+	_, err = c.GetSecret(ctx, v1alpha1.SecretRef{Key: "/default/missing/username"})
+	assert.ErrorIs(t, err, v1alpha1.ErrKeyNotFound)
+
+	// Get invalid
+	_, err = c.GetSecret(ctx, v1alpha1.SecretRef{Key: "/default/username"})
+	assert.Error(t, err)
+
+	// Set into new and existing secret
+	require.NoError(t, c.SetSecret(ctx, v1alpha1.SecretRef{Key: "/apps/api/token"}, []byte("token")))
+	require.NoError(t, c.SetSecret(ctx, v1alpha1.SecretRef{Key: "/default/db/host"}, []byte("localhost")))
+
+	value, err = c.GetSecret(ctx, v1alpha1.SecretRef{Key: "/apps/api/token"})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("token"), value)
+
+	value, err = c.GetSecret(ctx, v1alpha1.SecretRef{Key: "/default/db/password"})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("secret"), value)
+
+	// List
+	tests := []struct {
+		name  string
+		query v1alpha1.SecretQuery
+		want  []string
+	}{
+		{
+			name:  "All namespaces",
+			query: v1alpha1.SecretQuery{Key: v1alpha1.Query{Regexp: ".*"}},
+			want:  []string{"/apps/api/token", "/default/db/host", "/default/db/password", "/default/db/username"},
+		},
+		{
+			name:  "Single namespace",
+			query: v1alpha1.SecretQuery{Path: ptr("/default"), Key: v1alpha1.Query{Regexp: "(username|password)"}},
+			want:  []string{"/default/db/password", "/default/db/username"},
+		},
+		{
+			name:  "Single secret",
+			query: v1alpha1.SecretQuery{Path: ptr("/default/db/"), Key: v1alpha1.Query{Regexp: "host"}},
+			want:  []string{"/default/db/host"},
+		},
+	}
+
+	for _, tt := range tests {
+		ttp := tt
+		t.Run(ttp.name, func(t *testing.T) {
+			refs, err := c.ListSecretKeys(ctx, ttp.query)
+			require.NoError(t, err)
+
+			var got []string
+			for _, ref := range refs {
+				got = append(got, ref.Key)
+			}
+			assert.ElementsMatch(t, ttp.want, got)
+		})
+	}
+
+	// Delete data key, then the secret with its last data key
+	require.NoError(t, c.DeleteSecret(ctx, v1alpha1.SecretRef{Key: "/default/db/host"}))
+	_, err = c.GetSecret(ctx, v1alpha1.SecretRef{Key: "/default/db/host"})
+	assert.ErrorIs(t, err, v1alpha1.ErrKeyNotFound)
+	value, err = c.GetSecret(ctx, v1alpha1.SecretRef{Key: "/default/db/username"})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("admin"), value)
+
+	require.NoError(t, c.DeleteSecret(ctx, v1alpha1.SecretRef{Key: "/apps/api/token"}))
+	_, err = c.apiClient.CoreV1().Secrets("apps").Get(ctx, "api", metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+
+	err = c.DeleteSecret(ctx, v1alpha1.SecretRef{Key: "/apps/api/token"})
+	assert.ErrorIs(t, err, v1alpha1.ErrKeyNotFound)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,73 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"github.com/bank-vaults/secret-sync/pkg/apis/v1alpha1"
+)
+
+type Provider struct{}
+
+func (p *Provider) NewClient(_ context.Context, backend v1alpha1.SecretStoreSpec) (v1alpha1.StoreClient, error) {
+	restConfig, err := restConfigFor(backend.Kubernetes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubernetes config: %w", err)
+	}
+
+	apiClient, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create kubernetes client: %w", err)
+	}
+
+	return &client{
+		apiClient: apiClient,
+	}, nil
+}
+
+func (p *Provider) Validate(backend v1alpha1.SecretStoreSpec) error {
+	if backend.Kubernetes == nil {
+		return errors.New("empty Kubernetes config")
+	}
+	if backend.Kubernetes.Context != "" && backend.Kubernetes.KubeconfigPath == "" {
+		return errors.New("empty .Kubernetes.KubeconfigPath, required for .Kubernetes.Context")
+	}
+
+	return nil
+}
+
+func restConfigFor(store *v1alpha1.KubernetesStore) (*rest.Config, error) {
+	if store.KubeconfigPath == "" {
+		return rest.InClusterConfig()
+	}
+
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: store.KubeconfigPath},
+		&clientcmd.ConfigOverrides{CurrentContext: store.Context},
+	).ClientConfig()
+}
+
+func init() {
+	v1alpha1.Register(&Provider{}, &v1alpha1.SecretStoreSpec{
+		Kubernetes: &v1alpha1.KubernetesStore{},
+	})
+}
@@ -17,10 +17,15 @@ package provider
 import (
 	"context"
 	"fmt"
+	"io"
 
 	"github.com/bank-vaults/secret-sync/pkg/apis/v1alpha1"
 	// Register providers
+	_ "github.com/bank-vaults/secret-sync/pkg/provider/awssecretsmanager"
+	_ "github.com/bank-vaults/secret-sync/pkg/provider/azurekeyvault"
 	_ "github.com/bank-vaults/secret-sync/pkg/provider/file"
+	_ "github.com/bank-vaults/secret-sync/pkg/provider/gcpsecretmanager"
+	_ "github.com/bank-vaults/secret-sync/pkg/provider/kubernetes"
 	_ "github.com/bank-vaults/secret-sync/pkg/provider/vault"
 )
 
@@ -45,3 +50,13 @@ func NewClient(ctx context.Context, backend *v1alpha1.SecretStoreSpec) (v1alpha1
 
 	return client, nil
 }
+
+// Close releases resources held by a store client, e.g. revokes credentials it created.
+// Clients which do not hold any resources are ignored.
+func Close(client v1alpha1.StoreClient) error {
+	if closer, ok := client.(io.Closer); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,174 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	vaultapi "github.com/hashicorp/vault/api"
+
+	"github.com/bank-vaults/secret-sync/pkg/apis/v1alpha1"
+)
+
+// login authenticates to Vault using the configured auth method and returns the issued token.
+func login(ctx context.Context, apiClient *vaultapi.Client, auth *v1alpha1.VaultAuth) (*vaultapi.SecretAuth, error) {
+	loginPath, loginData, err := loginRequest(auth)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := apiClient.Logical().WriteWithContext(ctx, loginPath, loginData)
+	if err != nil {
+		return nil, fmt.Errorf("api login request failed: %w", err)
+	}
+
+	if response == nil || response.Auth == nil || response.Auth.ClientToken == "" {
+		return nil, fmt.Errorf("api login request returned empty token for %s", loginPath)
+	}
+
+	return response.Auth, nil
+}
+
+// loginRequest returns API path and data to log in with the configured auth method.
+func loginRequest(auth *v1alpha1.VaultAuth) (string, map[string]interface{}, error) {
+	switch {
+	case auth.AppRole != nil:
+		roleID := auth.AppRole.RoleID
+		if auth.AppRole.RoleIDPath != "" {
+			var err error
+			if roleID, err = readFileValue(auth.AppRole.RoleIDPath); err != nil {
+				return "", nil, err
+			}
+		}
+
+		secretID, err := readFileValue(auth.AppRole.SecretIDPath)
+		if err != nil {
+			return "", nil, err
+		}
+
+		return loginPath(auth.AppRole.GetMountPath()), map[string]interface{}{
+			"role_id":   roleID,
+			"secret_id": secretID,
+		}, nil
+
+	case auth.Kubernetes != nil:
+		jwt, err := readFileValue(auth.Kubernetes.GetTokenPath())
+		if err != nil {
+			return "", nil, err
+		}
+
+		return loginPath(auth.Kubernetes.GetMountPath()), map[string]interface{}{
+			"role": auth.Kubernetes.Role,
+			"jwt":  jwt,
+		}, nil
+
+	case auth.JWT != nil:
+		jwt, err := readFileValue(auth.JWT.TokenPath)
+		if err != nil {
+			return "", nil, err
+		}
+
+		loginData := map[string]interface{}{
+			"jwt": jwt,
+		}
+		if auth.JWT.Role != "" {
+			loginData["role"] = auth.JWT.Role
+		}
+
+		return loginPath(auth.JWT.GetMountPath()), loginData, nil
+
+	case auth.UserPass != nil:
+		password, err := readFileValue(auth.UserPass.PasswordPath)
+		if err != nil {
+			return "", nil, err
+		}
+
+		return loginPath(auth.UserPass.GetMountPath()) + "/" + auth.UserPass.Username, map[string]interface{}{
+			"password": password,
+		}, nil
+	}
+
+	return "", nil, errors.New("no auth method specified")
+}
+
+// validateAuth checks that exactly one auth method is configured with all required fields.
+func validateAuth(auth *v1alpha1.VaultAuth) error {
+	var methods []string
+	if auth.AppRole != nil {
+		methods = append(methods, "AppRole")
+		if auth.AppRole.RoleID == "" && auth.AppRole.RoleIDPath == "" {
+			return errors.New("empty .Vault.Auth.AppRole.RoleID and .Vault.Auth.AppRole.RoleIDPath")
+		}
+		if auth.AppRole.RoleID != "" && auth.AppRole.RoleIDPath != "" {
+			return errors.New("only one of .Vault.Auth.AppRole.RoleID and .Vault.Auth.AppRole.RoleIDPath can be specified")
+		}
+		if auth.AppRole.SecretIDPath == "" {
+			return errors.New("empty .Vault.Auth.AppRole.SecretIDPath")
+		}
+	}
+	if auth.Kubernetes != nil {
+		methods = append(methods, "Kubernetes")
+		if auth.Kubernetes.Role == "" {
+			return errors.New("empty .Vault.Auth.Kubernetes.Role")
+		}
+	}
+	if auth.JWT != nil {
+		methods = append(methods, "JWT")
+		if auth.JWT.TokenPath == "" {
+			return errors.New("empty .Vault.Auth.JWT.TokenPath")
+		}
+	}
+	if auth.UserPass != nil {
+		methods = append(methods, "UserPass")
+		if auth.UserPass.Username == "" {
+			return errors.New("empty .Vault.Auth.UserPass.Username")
+		}
+		if auth.UserPass.PasswordPath == "" {
+			return errors.New("empty .Vault.Auth.UserPass.PasswordPath")
+		}
+	}
+
+	switch len(methods) {
+	case 0:
+		return errors.New("empty .Vault.Auth, requires one auth method")
+	case 1:
+		return nil
+	default:
+		return fmt.Errorf("only one .Vault.Auth method can be specified, got %s", strings.Join(methods, ", "))
+	}
+}
+
+func loginPath(mountPath string) string {
+	return fmt.Sprintf("auth/%s/login", strings.Trim(mountPath, "/"))
+}
+
+// readFileValue reads a credential from a local file, ignoring surrounding whitespace.
+func readFileValue(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read auth credential file: %w", err)
+	}
+
+	value := strings.TrimSpace(string(data))
+	if value == "" {
+		return "", fmt.Errorf("auth credential file %s is empty", path)
+	}
+
+	return value, nil
+}
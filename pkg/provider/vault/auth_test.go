@@ -0,0 +1,189 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vault
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bank-vaults/secret-sync/pkg/apis/v1alpha1"
+)
+
+func TestClientAuth(t *testing.T) {
+	dir := t.TempDir()
+	credential := func(name, value string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(value+"\n"), 0o600))
+		return path
+	}
+
+	tests := []struct {
+		name      string
+		auth      *v1alpha1.VaultAuth
+		wantPath  string
+		wantLogin map[string]interface{}
+	}{
+		{
+			name: "AppRole",
+			auth: &v1alpha1.VaultAuth{AppRole: &v1alpha1.VaultAppRoleAuth{
+				RoleIDPath:   credential("role-id", "role"),
+				SecretIDPath: credential("secret-id", "secret"),
+			}},
+			wantPath:  "auth/approle/login",
+			wantLogin: map[string]interface{}{"role_id": "role", "secret_id": "secret"},
+		},
+		{
+			name: "Kubernetes",
+			auth: &v1alpha1.VaultAuth{Kubernetes: &v1alpha1.VaultKubernetesAuth{
+				MountPath: "k8s",
+				Role:      "secret-sync",
+				TokenPath: credential("sa-token", "sa-jwt"),
+			}},
+			wantPath:  "auth/k8s/login",
+			wantLogin: map[string]interface{}{"role": "secret-sync", "jwt": "sa-jwt"},
+		},
+		{
+			name: "JWT",
+			auth: &v1alpha1.VaultAuth{JWT: &v1alpha1.VaultJWTAuth{
+				Role:      "ci",
+				TokenPath: credential("ci-token", "ci-jwt"),
+			}},
+			wantPath:  "auth/jwt/login",
+			wantLogin: map[string]interface{}{"role": "ci", "jwt": "ci-jwt"},
+		},
+		{
+			name: "UserPass",
+			auth: &v1alpha1.VaultAuth{UserPass: &v1alpha1.VaultUserPassAuth{
+				Username:     "admin",
+				PasswordPath: credential("password", "password"),
+			}},
+			wantPath:  "auth/userpass/login/admin",
+			wantLogin: map[string]interface{}{"password": "password"},
+		},
+	}
+
+	for _, tt := range tests {
+		ttp := tt
+		t.Run(ttp.name, func(t *testing.T) {
+			ctx := context.Background()
+			server := newFakeVault(t, kvVersion2)
+			server.secrets["db"] = []fakeVersion{{data: map[string]interface{}{"password": "secret"}}}
+
+			store := v1alpha1.SecretStoreSpec{
+				Vault: &v1alpha1.VaultStore{
+					Address:   server.URL,
+					StorePath: "secret",
+					Auth:      ttp.auth,
+				},
+			}
+			require.NoError(t, (&Provider{}).Validate(store))
+
+			storeClient, err := (&Provider{}).NewClient(ctx, store)
+			require.NoError(t, err)
+
+			require.Len(t, server.logins, 1)
+			assert.Equal(t, ttp.wantPath, server.logins[0].path)
+			assert.Equal(t, ttp.wantLogin, server.logins[0].data)
+
+			// Logged in token is used for requests
+			value, err := storeClient.GetSecret(ctx, v1alpha1.SecretRef{Key: "db/password"})
+			require.NoError(t, err)
+			assert.Equal(t, []byte("secret"), value)
+
+			// Logged in token is revoked on close
+			require.NoError(t, storeClient.(io.Closer).Close())
+			assert.Equal(t, 1, server.revocations)
+		})
+	}
+}
+
+func TestValidateAuth(t *testing.T) {
+	tests := []struct {
+		name    string
+		store   v1alpha1.VaultStore
+		wantErr bool
+	}{
+		{
+			name:  "Static token",
+			store: v1alpha1.VaultStore{AuthPath: "userpass", Token: "token"},
+		},
+		{
+			name:  "Token from file",
+			store: v1alpha1.VaultStore{AuthPath: "kubernetes", TokenPath: "/tmp/token"},
+		},
+		{
+			name: "Single method",
+			store: v1alpha1.VaultStore{Auth: &v1alpha1.VaultAuth{
+				Kubernetes: &v1alpha1.VaultKubernetesAuth{Role: "secret-sync"},
+			}},
+		},
+		{
+			name:    "No method",
+			store:   v1alpha1.VaultStore{Auth: &v1alpha1.VaultAuth{}},
+			wantErr: true,
+		},
+		{
+			name: "Multiple methods",
+			store: v1alpha1.VaultStore{Auth: &v1alpha1.VaultAuth{
+				Kubernetes: &v1alpha1.VaultKubernetesAuth{Role: "secret-sync"},
+				JWT:        &v1alpha1.VaultJWTAuth{TokenPath: "/tmp/jwt"},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "Method with static token",
+			store: v1alpha1.VaultStore{Token: "token", Auth: &v1alpha1.VaultAuth{
+				Kubernetes: &v1alpha1.VaultKubernetesAuth{Role: "secret-sync"},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "AppRole without secret ID",
+			store: v1alpha1.VaultStore{Auth: &v1alpha1.VaultAuth{
+				AppRole: &v1alpha1.VaultAppRoleAuth{RoleID: "role"},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "UserPass without password",
+			store: v1alpha1.VaultStore{Auth: &v1alpha1.VaultAuth{
+				UserPass: &v1alpha1.VaultUserPassAuth{Username: "admin"},
+			}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		ttp := tt
+		t.Run(ttp.name, func(t *testing.T) {
+			ttp.store.Address = "http://127.0.0.1:8200"
+			ttp.store.StorePath = "secret"
+
+			err := (&Provider{}).Validate(v1alpha1.SecretStoreSpec{Vault: &ttp.store})
+			if ttp.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+		})
+	}
+}
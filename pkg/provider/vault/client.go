@@ -16,55 +16,71 @@ package vault
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
 
 	"github.com/bank-vaults/vault-sdk/vault"
 	"github.com/spf13/cast"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/bank-vaults/secret-sync/pkg/apis/v1alpha1"
 )
 
+// fieldSelector separates the secret path from a field in secret key mode, e.g. "path/to/key#field".
+const fieldSelector = "#"
+
 type client struct {
-	apiClient  *vault.Client
-	apiKeyPath string
+	apiClient   *vault.Client
+	tokens      *tokenManager
+	apiKeyPath  string
+	kvVersion   int
+	keyMode     v1alpha1.VaultKeyMode
+	writeMode   v1alpha1.VaultWriteMode
+	deleteMode  v1alpha1.VaultDeleteMode
+	checkAndSet bool
+
+	// readVersions maps secret paths to versions seen by the latest read,
+	// so that check-and-set writes fail if the secret changed since it was read.
+	readVersions sync.Map
 }
 
 func (c *client) GetSecret(ctx context.Context, key v1alpha1.SecretRef) ([]byte, error) {
-	// Get secret from API
-	response, err := c.apiClient.RawClient().Logical().ReadWithContext(
-		ctx,
-		fmt.Sprintf("%s/data/%s", c.apiKeyPath, pathForKey(key)),
-	)
+	secretPath, err := c.pathForKey(key)
 	if err != nil {
-		return nil, fmt.Errorf("api get request failed: %w", err)
+		return nil, err
 	}
 
-	if response == nil || response.Data == nil {
-		// TODO: check if this is valid err return
-		return nil, v1alpha1.ErrKeyNotFound
+	// Get secret from API
+	data, version, err := c.readSecret(ctx, secretPath, key.GetVersion())
+	if key.GetVersion() == "" {
+		c.observeVersion(secretPath, version, err)
 	}
-
-	// Extract key value data
-	secretData, ok := response.Data["data"]
-	if !ok || secretData == nil {
-		return nil, errors.New("api get returned empty data")
+	if err != nil {
+		return nil, err
 	}
 
-	data, err := cast.ToStringMapE(secretData)
-	if err != nil {
-		return nil, fmt.Errorf("api get request findind data: %w", err)
+	// Return whole secret if no field is selected
+	field := c.fieldForKey(key)
+	if field == "" {
+		return json.Marshal(data)
 	}
 
-	// Get name
-	keyData, ok := data[key.GetName()]
+	// Get field
+	keyData, ok := data[field]
 	if !ok {
-		return nil, fmt.Errorf("could not find %s for in get response", key.GetName())
+		return nil, fmt.Errorf("could not find %s for in get response", field)
+	}
+
+	if value, ok := keyData.(string); ok {
+		return []byte(value), nil
 	}
 
-	return []byte(keyData.(string)), nil
+	return json.Marshal(keyData)
 }
 
 func (c *client) ListSecretKeys(ctx context.Context, query v1alpha1.SecretQuery) ([]v1alpha1.SecretRef, error) {
@@ -74,128 +90,326 @@ func (c *client) ListSecretKeys(ctx context.Context, query v1alpha1.SecretQuery)
 		queryPath = *query.Path
 	}
 
-	// List API request
-	response, err := c.apiClient.RawClient().Logical().ListWithContext(
+	// Stream keys from queried dir and nested dirs
+	keys := make(chan string)
+	listErr := make(chan error, 1)
+	go func() {
+		defer close(keys)
+		listErr <- c.listKeys(ctx, queryPath, query, keys)
+	}()
+
+	// Add keys that match regexp query
+	var result []v1alpha1.SecretRef
+	for key := range keys {
+		keyName := key[strings.LastIndex(key, "/")+1:]
+		if matches, _ := regexp.MatchString(query.Key.Regexp, keyName); matches {
+			result = append(result, v1alpha1.SecretRef{
+				Key: key,
+			})
+		}
+	}
+	if err := <-listErr; err != nil {
+		return nil, err
+	}
+
+	// Nested dirs are listed concurrently
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+
+	return result, nil
+}
+
+func (c *client) SetSecret(ctx context.Context, key v1alpha1.SecretRef, value []byte) error {
+	secretPath, err := c.pathForKey(key)
+	if err != nil {
+		return err
+	}
+
+	// Get fields to write, whole secret is written as a map
+	data := map[string]interface{}{}
+	if field := c.fieldForKey(key); field != "" {
+		data[field] = string(value)
+	} else if err := json.Unmarshal(value, &data); err != nil {
+		return fmt.Errorf("value for %s must be a JSON map when no field is selected: %w", key.Key, err)
+	}
+
+	// Merge into existing secret fields
+	if c.writeMode == v1alpha1.VaultWriteModeMerge {
+		return c.mergeSecret(ctx, secretPath, data)
+	}
+
+	writeRequest := c.writeData(data)
+
+	// Guard against updates since the secret was read using check-and-set
+	if c.checkAndSet {
+		version, err := c.casVersion(ctx, secretPath)
+		if err != nil {
+			return err
+		}
+
+		writeRequest["options"] = map[string]interface{}{"cas": version}
+	}
+
+	// Write secret to API
+	response, err := c.apiClient.RawClient().Logical().WriteWithContext(
 		ctx,
-		fmt.Sprintf("%s/metadata/%s", c.apiKeyPath, queryPath),
+		c.dataPath(secretPath),
+		writeRequest,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("api list request failed: %w", err)
+		if isCASMismatch(err) {
+			return fmt.Errorf("api set request failed for %s: %w", key.Key, v1alpha1.ErrConflict)
+		}
+
+		return fmt.Errorf("api set request failed: %w", classifyError(err))
 	}
 
-	if response == nil || response.Data == nil {
-		// TODO: check if this is valid err return
-		return nil, v1alpha1.ErrKeyNotFound
+	// Later writes are conditioned on the written version
+	if c.checkAndSet && response != nil && response.Data != nil {
+		c.readVersions.Store(secretPath, cast.ToInt(response.Data["version"]))
 	}
 
-	// Read from response
-	listData, ok := response.Data["keys"]
-	if !ok || listData == nil {
-		return nil, errors.New("api list returned empty data")
+	return nil
+}
+
+// observeVersion records the secret version seen by a read for check-and-set writes.
+// Missing and deleted secrets are recorded with their version, 0 if they never existed.
+func (c *client) observeVersion(secretPath string, version int, err error) {
+	if !c.checkAndSet {
+		return
 	}
 
-	listSlice, ok := listData.([]interface{})
-	if !ok || listSlice == nil {
-		return nil, errors.New("api list returned invalid data")
+	if err == nil || errors.Is(err, v1alpha1.ErrKeyNotFound) || errors.Is(err, v1alpha1.ErrKeyVersionDeleted) {
+		c.readVersions.Store(secretPath, version)
+		return
 	}
 
-	// Extract keys from response
-	var result []v1alpha1.SecretRef
-	for _, listKey := range listSlice {
-		// Skip values in KV store that are not keys (marked by a suffix '/').
-		keyName := fmt.Sprintf("%v", listKey)
-		if strings.HasSuffix(keyName, "/") {
-			continue
+	c.readVersions.Delete(secretPath)
+}
+
+// casVersion returns the secret version for a check-and-set write. This is the version seen
+// by the latest read, or the current version if the secret was not read by the client.
+func (c *client) casVersion(ctx context.Context, secretPath string) (int, error) {
+	if version, ok := c.readVersions.Load(secretPath); ok {
+		return version.(int), nil
+	}
+
+	return c.currentVersion(ctx, secretPath)
+}
+
+func (c *client) DeleteSecret(ctx context.Context, key v1alpha1.SecretRef) error {
+	secretPath, err := c.pathForKey(key)
+	if err != nil {
+		return err
+	}
+
+	// Get current fields, deleted secrets are treated as missing
+	current, version, err := c.readSecret(ctx, secretPath, "")
+	if err != nil {
+		if errors.Is(err, v1alpha1.ErrKeyVersionDeleted) {
+			return v1alpha1.ErrKeyNotFound
 		}
 
-		// Add key if it matches regexp query
-		if matches, _ := regexp.MatchString(query.Key.Regexp, keyName); matches {
-			result = append(result, v1alpha1.SecretRef{
-				Key: fmt.Sprintf("%s%s", queryPath, keyName),
-			})
+		return err
+	}
+
+	// Only remove the selected field if other fields remain
+	if field := c.fieldForKey(key); field != "" {
+		if _, ok := current[field]; !ok {
+			return v1alpha1.ErrKeyNotFound
+		}
+
+		if len(current) > 1 {
+			delete(current, field)
+
+			writeRequest := c.writeData(current)
+			if c.kvVersion == kvVersion2 {
+				writeRequest["options"] = map[string]interface{}{"cas": version}
+			}
+
+			_, err = c.apiClient.RawClient().Logical().WriteWithContext(ctx, c.dataPath(secretPath), writeRequest)
+			if err != nil {
+				if isCASMismatch(err) {
+					return fmt.Errorf("api delete request failed for %s: %w", key.Key, v1alpha1.ErrConflict)
+				}
+
+				return fmt.Errorf("api delete request failed: %w", classifyError(err))
+			}
+
+			return nil
 		}
 	}
 
-	return result, nil
+	// Delete whole secret
+	_, err = c.apiClient.RawClient().Logical().DeleteWithContext(ctx, c.deletePath(secretPath))
+	if err != nil {
+		return fmt.Errorf("api delete request failed: %w", classifyError(err))
+	}
+
+	return nil
 }
 
-func (c *client) SetSecret(ctx context.Context, key v1alpha1.SecretRef, value []byte) error {
+// mergeSecret writes data fields into an existing secret while preserving its other fields.
+// For KV v2, read-modify-write is guarded with check-and-set. Concurrent updates are reported
+// as temporary conflicts, so that the merge is retried with backoff by the sync retry policy.
+func (c *client) mergeSecret(ctx context.Context, secretPath string, data map[string]interface{}) error {
+	// Read current fields and version
+	current, version, err := c.readSecret(ctx, secretPath, "")
+	switch {
+	case errors.Is(err, v1alpha1.ErrKeyNotFound), errors.Is(err, v1alpha1.ErrKeyVersionDeleted):
+		current = map[string]interface{}{}
+	case err != nil:
+		return err
+	}
+
+	// Merge
+	for field, value := range data {
+		current[field] = value
+	}
+
+	writeRequest := c.writeData(current)
+	if c.kvVersion == kvVersion2 {
+		writeRequest["options"] = map[string]interface{}{"cas": version}
+	}
+
 	// Write secret to API
-	_, err := c.apiClient.RawClient().Logical().WriteWithContext(
-		ctx,
-		fmt.Sprintf("%s/data/%s", c.apiKeyPath, pathForKey(key)),
-		map[string]interface{}{
-			"data": map[string]interface{}{
-				key.GetName(): string(value),
-			},
-		},
-	)
+	_, err = c.apiClient.RawClient().Logical().WriteWithContext(ctx, c.dataPath(secretPath), writeRequest)
 	if err != nil {
-		return fmt.Errorf("api set request failed: %w", err)
+		if isCASMismatch(err) {
+			return fmt.Errorf("api set request failed for %s: %w: %w", secretPath, v1alpha1.ErrTransient, v1alpha1.ErrConflict)
+		}
+
+		return fmt.Errorf("api set request failed: %w", classifyError(err))
 	}
 
 	return nil
 }
 
-// recursiveList will recursively list all items in a Vault.
-// Not used since it has high memory footprint and does not handle search.
-// It could (potentially) be useful.
-// DEPRECATED
-func (c *client) recursiveList(ctx context.Context, path string) ([]v1alpha1.SecretRef, error) { //nolint: unused
+// Close stops token refresh and revokes tokens created by the client.
+func (c *client) Close() error {
+	defer c.apiClient.Close()
+
+	return c.tokens.Close()
+}
+
+// listKeys sends all keys from a dir to the keys channel as they are listed.
+// Nested dirs are listed concurrently if included by query depth,
+// with at most maxListConcurrency API list requests in flight.
+func (c *client) listKeys(ctx context.Context, path string, query v1alpha1.SecretQuery, keys chan<- string) error {
+	listGroup, listCtx := errgroup.WithContext(ctx)
+	listSem := make(chan struct{}, maxListConcurrency)
+
+	var listDir func(dirPath string, depth int)
+	listDir = func(dirPath string, depth int) {
+		listGroup.Go(func() error {
+			// Wait for a free slot
+			select {
+			case listSem <- struct{}{}:
+			case <-listCtx.Done():
+				return listCtx.Err()
+			}
+
+			entries, err := c.listDirEntries(listCtx, dirPath)
+			<-listSem
+			if err != nil {
+				// Nested dirs can be removed while listing
+				if depth > 0 && errors.Is(err, v1alpha1.ErrKeyNotFound) {
+					return nil
+				}
+
+				return err
+			}
+
+			// A key in a KV store can be either a secret or a dir (marked by a suffix '/').
+			// TODO: Track changes to Vault API https://github.com/hashicorp/vault/issues/5275.
+			for _, entry := range entries {
+				if strings.HasSuffix(entry, "/") {
+					if query.IncludesDepth(depth + 1) {
+						listDir(dirPath+entry, depth+1)
+					}
+					continue
+				}
+
+				select {
+				case keys <- dirPath + entry:
+				case <-listCtx.Done():
+					return listCtx.Err()
+				}
+			}
+
+			return nil
+		})
+	}
+	listDir(path, 0)
+
+	return listGroup.Wait()
+}
+
+// listDirEntries returns keys and dirs (marked by a suffix '/') from a dir.
+func (c *client) listDirEntries(ctx context.Context, dirPath string) ([]string, error) {
 	// List API request
 	response, err := c.apiClient.RawClient().Logical().ListWithContext(
 		ctx,
-		fmt.Sprintf("%s/metadata/%s", c.apiKeyPath, path),
+		c.listPath(dirPath),
 	)
 	if err != nil {
-		return nil, fmt.Errorf("api list request failed: %w", err)
+		return nil, fmt.Errorf("api list request failed: %w", classifyError(err))
 	}
+
 	if response == nil || response.Data == nil {
-		return nil, errors.New("api list request returned empty response")
+		// TODO: check if this is valid err return
+		return nil, v1alpha1.ErrKeyNotFound
 	}
 
 	// Read from response
 	listData, ok := response.Data["keys"]
 	if !ok || listData == nil {
-		return nil, fmt.Errorf("api list returned empty data for key %s", path)
+		return nil, errors.New("api list returned empty data")
 	}
 
 	listSlice, ok := listData.([]interface{})
 	if !ok || listSlice == nil {
-		return nil, fmt.Errorf("api list returned invalid data for key %s", path)
+		return nil, errors.New("api list returned invalid data")
 	}
 
-	// Extract keys from response.
-	// A key in a KV store can be either a secret or a dir (marked by a suffix '/').
-	// For dirs, keep recursively listing them and adding their result results.
-	// TODO: Track changes to Vault API https://github.com/hashicorp/vault/issues/5275.
-	var result []v1alpha1.SecretRef
+	entries := make([]string, 0, len(listSlice))
 	for _, listKey := range listSlice {
-		subKey := fmt.Sprintf("%s%v", path, listKey)
-		if !strings.HasSuffix(subKey, "/") { // key
-			result = append(result, v1alpha1.SecretRef{
-				Key: subKey,
-			})
-		} else { // dir
-			// Recursive list
-			subKeys, err := c.recursiveList(ctx, subKey)
-			if err != nil {
-				return nil, err
-			}
-
-			// Add to resulting keys
-			result = append(result, subKeys...)
-		}
+		entries = append(entries, fmt.Sprintf("%v", listKey))
 	}
 
-	return result, nil
+	return entries, nil
 }
 
-func pathForKey(key v1alpha1.SecretRef) string {
+// pathForKey returns secret path for a key based on the key mode.
+func (c *client) pathForKey(key v1alpha1.SecretRef) (string, error) {
+	if c.keyMode == v1alpha1.VaultKeyModeSecret {
+		key, _ = splitField(key)
+		return strings.Join(append(key.GetPath(), key.GetName()), "/"), nil
+	}
+
 	// If key has no path, return name as path
 	if len(key.GetPath()) == 0 {
-		return key.GetName()
+		return key.GetName(), nil
+	}
+
+	return strings.Join(key.GetPath(), "/"), nil
+}
+
+// fieldForKey returns secret field for a key based on the key mode.
+// Empty field selects the whole secret.
+func (c *client) fieldForKey(key v1alpha1.SecretRef) string {
+	if c.keyMode == v1alpha1.VaultKeyModeSecret {
+		_, field := splitField(key)
+		return field
 	}
 
-	return strings.Join(key.GetPath(), "/")
+	return key.GetName()
+}
+
+// splitField returns key without field selector and the selected field,
+// e.g. splitField("/path/to/key#field") returns "/path/to/key" and "field".
+func splitField(key v1alpha1.SecretRef) (v1alpha1.SecretRef, string) {
+	keyPath, field, _ := strings.Cut(key.Key, fieldSelector)
+	key.Key = keyPath
+
+	return key, field
 }
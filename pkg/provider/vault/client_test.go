@@ -0,0 +1,840 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vault
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+
+	vaultapi "github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bank-vaults/secret-sync/pkg/apis/v1alpha1"
+)
+
+func TestClient(t *testing.T) {
+	for _, kvVersion := range []int{kvVersion1, kvVersion2} {
+		kvVersion := kvVersion
+		t.Run("KV version "+strconv.Itoa(kvVersion), func(t *testing.T) {
+			ctx := context.Background()
+			server := newFakeVault(t, kvVersion)
+
+			// Use auto-detection
+			storeClient := newTestClient(t, server, nil)
+			require.Equal(t, kvVersion, storeClient.(*client).kvVersion)
+
+			// Set
+			require.NoError(t, storeClient.SetSecret(ctx, v1alpha1.SecretRef{Key: "db/username"}, []byte("admin")))
+			require.NoError(t, storeClient.SetSecret(ctx, v1alpha1.SecretRef{Key: "app/token"}, []byte("secret")))
+
+			// Get
+			value, err := storeClient.GetSecret(ctx, v1alpha1.SecretRef{Key: "db/username"})
+			require.NoError(t, err)
+			assert.Equal(t, []byte("admin"), value)
+
+			_, err = storeClient.GetSecret(ctx, v1alpha1.SecretRef{Key: "missing/username"})
+			assert.ErrorIs(t, err, v1alpha1.ErrKeyNotFound)
+
+			// List
+			refs, err := storeClient.ListSecretKeys(ctx, v1alpha1.SecretQuery{Key: v1alpha1.Query{Regexp: "d.*"}})
+			require.NoError(t, err)
+			assert.Equal(t, []v1alpha1.SecretRef{{Key: "db"}}, refs)
+		})
+	}
+}
+
+func TestClientRecursiveList(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeVault(t, kvVersion2)
+	for _, secretPath := range []string{"app/db", "app/api", "app/nested/token", "app/nested/deep/key", "other"} {
+		server.secrets[secretPath] = []fakeVersion{{data: map[string]interface{}{"value": "value"}}}
+	}
+	storeClient := newTestClient(t, server, func(store *v1alpha1.VaultStore) {
+		store.KeyMode = v1alpha1.VaultKeyModeSecret
+	})
+
+	tests := []struct {
+		name  string
+		query v1alpha1.SecretQuery
+		want  []v1alpha1.SecretRef
+	}{
+		{
+			name:  "Non-recursive",
+			query: v1alpha1.SecretQuery{Path: ptr("app/"), Key: v1alpha1.Query{Regexp: ".*"}},
+			want:  []v1alpha1.SecretRef{{Key: "app/api"}, {Key: "app/db"}},
+		},
+		{
+			name:  "Recursive",
+			query: v1alpha1.SecretQuery{Path: ptr("app/"), Key: v1alpha1.Query{Regexp: ".*"}, Recursive: ptr(true)},
+			want:  []v1alpha1.SecretRef{{Key: "app/api"}, {Key: "app/db"}, {Key: "app/nested/deep/key"}, {Key: "app/nested/token"}},
+		},
+		{
+			name:  "Recursive with max depth",
+			query: v1alpha1.SecretQuery{Path: ptr("app/"), Key: v1alpha1.Query{Regexp: ".*"}, Recursive: ptr(true), MaxDepth: 1},
+			want:  []v1alpha1.SecretRef{{Key: "app/api"}, {Key: "app/db"}, {Key: "app/nested/token"}},
+		},
+		{
+			name:  "Recursive with key name query",
+			query: v1alpha1.SecretQuery{Key: v1alpha1.Query{Regexp: "^(key|other)$"}, Recursive: ptr(true)},
+			want:  []v1alpha1.SecretRef{{Key: "app/nested/deep/key"}, {Key: "other"}},
+		},
+	}
+
+	for _, tt := range tests {
+		ttp := tt
+		t.Run(ttp.name, func(t *testing.T) {
+			refs, err := storeClient.ListSecretKeys(ctx, ttp.query)
+			require.NoError(t, err)
+			assert.Equal(t, ttp.want, refs)
+		})
+	}
+}
+
+func TestClientDeleteSecret(t *testing.T) {
+	tests := []struct {
+		name       string
+		kvVersion  int
+		deleteMode v1alpha1.VaultDeleteMode
+		wantStored bool // if secret versions remain stored
+	}{
+		{name: "KV version 1", kvVersion: kvVersion1},
+		{name: "KV version 2 soft delete", kvVersion: kvVersion2, wantStored: true},
+		{name: "KV version 2 destroy", kvVersion: kvVersion2, deleteMode: v1alpha1.VaultDeleteModeDestroy},
+	}
+
+	for _, tt := range tests {
+		ttp := tt
+		t.Run(ttp.name, func(t *testing.T) {
+			ctx := context.Background()
+			server := newFakeVault(t, ttp.kvVersion)
+			server.secrets["app/db"] = []fakeVersion{
+				{data: map[string]interface{}{"username": "admin", "password": "secret"}},
+			}
+			storeClient := newTestClient(t, server, func(store *v1alpha1.VaultStore) {
+				store.KeyMode = v1alpha1.VaultKeyModeSecret
+				store.DeleteMode = ttp.deleteMode
+			})
+
+			// Removing a field keeps other fields
+			require.NoError(t, storeClient.DeleteSecret(ctx, v1alpha1.SecretRef{Key: "/app/db#password"}))
+			value, err := storeClient.GetSecret(ctx, v1alpha1.SecretRef{Key: "/app/db"})
+			require.NoError(t, err)
+			assert.JSONEq(t, `{"username":"admin"}`, string(value))
+
+			err = storeClient.DeleteSecret(ctx, v1alpha1.SecretRef{Key: "/app/db#password"})
+			assert.ErrorIs(t, err, v1alpha1.ErrKeyNotFound)
+
+			// Removing the last field deletes the secret
+			require.NoError(t, storeClient.DeleteSecret(ctx, v1alpha1.SecretRef{Key: "/app/db#username"}))
+			_, err = storeClient.GetSecret(ctx, v1alpha1.SecretRef{Key: "/app/db"})
+			assert.Error(t, err)
+
+			_, stored := server.secrets["app/db"]
+			assert.Equal(t, ttp.wantStored, stored)
+
+			err = storeClient.DeleteSecret(ctx, v1alpha1.SecretRef{Key: "/app/db"})
+			assert.ErrorIs(t, err, v1alpha1.ErrKeyNotFound)
+		})
+	}
+}
+
+func TestClientVersion(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeVault(t, kvVersion2)
+	server.secrets["db"] = []fakeVersion{
+		{data: map[string]interface{}{"password": "first"}},
+		{data: map[string]interface{}{"password": "second"}, deleted: true},
+		{data: map[string]interface{}{"password": "third"}, destroyed: true},
+		{data: map[string]interface{}{"password": "current"}},
+	}
+	storeClient := newTestClient(t, server, nil)
+
+	tests := []struct {
+		name    string
+		version *string
+		want    string
+		wantErr error
+	}{
+		{name: "Latest version", want: "current"},
+		{name: "Specific version", version: ptr("1"), want: "first"},
+		{name: "Deleted version", version: ptr("2"), wantErr: v1alpha1.ErrKeyVersionDeleted},
+		{name: "Destroyed version", version: ptr("3"), wantErr: v1alpha1.ErrKeyVersionDeleted},
+		{name: "Missing version", version: ptr("5"), wantErr: v1alpha1.ErrKeyNotFound},
+	}
+
+	for _, tt := range tests {
+		ttp := tt
+		t.Run(ttp.name, func(t *testing.T) {
+			value, err := storeClient.GetSecret(ctx, v1alpha1.SecretRef{Key: "db/password", Version: ttp.version})
+			if ttp.wantErr != nil {
+				assert.ErrorIs(t, err, ttp.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, []byte(ttp.want), value)
+		})
+	}
+}
+
+func TestClientSecretKeyMode(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeVault(t, kvVersion2)
+	storeClient := newTestClient(t, server, func(store *v1alpha1.VaultStore) {
+		store.KeyMode = v1alpha1.VaultKeyModeSecret
+	})
+
+	// Write whole secret from map
+	require.NoError(t, storeClient.SetSecret(ctx, v1alpha1.SecretRef{Key: "/app/db"}, []byte(`{"username":"admin","password":"secret"}`)))
+	assert.Equal(t, map[string]interface{}{"username": "admin", "password": "secret"}, server.secrets["app/db"][0].data)
+
+	// Write single field
+	require.NoError(t, storeClient.SetSecret(ctx, v1alpha1.SecretRef{Key: "/app/api#token"}, []byte("token")))
+	assert.Equal(t, map[string]interface{}{"token": "token"}, server.secrets["app/api"][0].data)
+
+	// Write non-map value without field
+	assert.Error(t, storeClient.SetSecret(ctx, v1alpha1.SecretRef{Key: "/app/raw"}, []byte("raw")))
+
+	// Read whole secret
+	value, err := storeClient.GetSecret(ctx, v1alpha1.SecretRef{Key: "/app/db"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"username":"admin","password":"secret"}`, string(value))
+
+	// Read single field
+	value, err = storeClient.GetSecret(ctx, v1alpha1.SecretRef{Key: "/app/db#password"})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("secret"), value)
+
+	// Field selector is only parsed in secret key mode, otherwise it is part of the field name
+	fieldClient := newTestClient(t, server, nil)
+	_, err = fieldClient.GetSecret(ctx, v1alpha1.SecretRef{Key: "/app/db#password"})
+	assert.Error(t, err)
+	require.NoError(t, fieldClient.SetSecret(ctx, v1alpha1.SecretRef{Key: "/app/key#hash"}, []byte("value")))
+	assert.Equal(t, map[string]interface{}{"key#hash": "value"}, server.secrets["app"][0].data)
+}
+
+func TestClientMergeWriteMode(t *testing.T) {
+	for _, kvVersion := range []int{kvVersion1, kvVersion2} {
+		kvVersion := kvVersion
+		t.Run("KV version "+strconv.Itoa(kvVersion), func(t *testing.T) {
+			ctx := context.Background()
+			server := newFakeVault(t, kvVersion)
+			server.secrets["app/db"] = []fakeVersion{
+				{data: map[string]interface{}{"owner": "team-a", "password": "old"}},
+			}
+			storeClient := newTestClient(t, server, func(store *v1alpha1.VaultStore) {
+				store.KeyMode = v1alpha1.VaultKeyModeSecret
+				store.WriteMode = v1alpha1.VaultWriteModeMerge
+			})
+
+			// Merge into existing and create new
+			require.NoError(t, storeClient.SetSecret(ctx, v1alpha1.SecretRef{Key: "/app/db#password"}, []byte("new")))
+			require.NoError(t, storeClient.SetSecret(ctx, v1alpha1.SecretRef{Key: "/app/api"}, []byte(`{"token":"token"}`)))
+
+			value, err := storeClient.GetSecret(ctx, v1alpha1.SecretRef{Key: "/app/db"})
+			require.NoError(t, err)
+			assert.JSONEq(t, `{"owner":"team-a","password":"new"}`, string(value))
+
+			value, err = storeClient.GetSecret(ctx, v1alpha1.SecretRef{Key: "/app/api"})
+			require.NoError(t, err)
+			assert.JSONEq(t, `{"token":"token"}`, string(value))
+		})
+	}
+
+	t.Run("Concurrent update", func(t *testing.T) {
+		ctx := context.Background()
+		server := newFakeVault(t, kvVersion2)
+		server.secrets["app/db"] = []fakeVersion{
+			{data: map[string]interface{}{"password": "old"}},
+		}
+		storeClient := newTestClient(t, server, func(store *v1alpha1.VaultStore) {
+			store.KeyMode = v1alpha1.VaultKeyModeSecret
+			store.WriteMode = v1alpha1.VaultWriteModeMerge
+		})
+
+		// Another writer adds a field between first read and write
+		concurrentWrites := 0
+		server.beforeWrite = func(secretPath string) {
+			if concurrentWrites == 0 {
+				concurrentWrites++
+				server.secrets[secretPath] = append(server.secrets[secretPath], fakeVersion{
+					data: map[string]interface{}{"password": "old", "owner": "team-b"},
+				})
+			}
+		}
+
+		// Conflict is reported as temporary so that it can be retried with backoff
+		err := storeClient.SetSecret(ctx, v1alpha1.SecretRef{Key: "/app/db#password"}, []byte("new"))
+		assert.ErrorIs(t, err, v1alpha1.ErrConflict)
+		assert.ErrorIs(t, err, v1alpha1.ErrTransient)
+
+		value, err := storeClient.GetSecret(ctx, v1alpha1.SecretRef{Key: "/app/db"})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"owner":"team-b","password":"old"}`, string(value))
+
+		// Retried merge preserves the concurrent update
+		require.NoError(t, storeClient.SetSecret(ctx, v1alpha1.SecretRef{Key: "/app/db#password"}, []byte("new")))
+
+		value, err = storeClient.GetSecret(ctx, v1alpha1.SecretRef{Key: "/app/db"})
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"owner":"team-b","password":"new"}`, string(value))
+	})
+}
+
+func TestClientCheckAndSet(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeVault(t, kvVersion2)
+	server.secrets["app/db"] = []fakeVersion{
+		{data: map[string]interface{}{"password": "old"}},
+	}
+	storeClient := newTestClient(t, server, func(store *v1alpha1.VaultStore) {
+		store.KeyMode = v1alpha1.VaultKeyModeSecret
+		store.CheckAndSet = true
+	})
+
+	// Update existing and create new
+	require.NoError(t, storeClient.SetSecret(ctx, v1alpha1.SecretRef{Key: "/app/db#password"}, []byte("new")))
+	require.NoError(t, storeClient.SetSecret(ctx, v1alpha1.SecretRef{Key: "/app/api#token"}, []byte("token")))
+	assert.Len(t, server.secrets["app/db"], 2)
+	assert.Len(t, server.secrets["app/api"], 1)
+
+	// Writes are conditioned on the version seen by the latest read
+	_, err := storeClient.GetSecret(ctx, v1alpha1.SecretRef{Key: "/app/db#password"})
+	require.NoError(t, err)
+	server.secrets["app/db"] = append(server.secrets["app/db"], fakeVersion{
+		data: map[string]interface{}{"password": "external"},
+	})
+	err = storeClient.SetSecret(ctx, v1alpha1.SecretRef{Key: "/app/db#password"}, []byte("newer"))
+	assert.ErrorIs(t, err, v1alpha1.ErrConflict)
+	assert.Len(t, server.secrets["app/db"], 3, "conflicted write is not retried")
+
+	// Written version is used for later writes
+	_, err = storeClient.GetSecret(ctx, v1alpha1.SecretRef{Key: "/app/db#password"})
+	require.NoError(t, err)
+	require.NoError(t, storeClient.SetSecret(ctx, v1alpha1.SecretRef{Key: "/app/db#password"}, []byte("newer")))
+	require.NoError(t, storeClient.SetSecret(ctx, v1alpha1.SecretRef{Key: "/app/db#password"}, []byte("newest")))
+
+	// Another writer updates the secret between version lookup and write
+	server.beforeWrite = func(secretPath string) {
+		server.secrets[secretPath] = append(server.secrets[secretPath], fakeVersion{
+			data: map[string]interface{}{"password": "concurrent"},
+		})
+	}
+
+	err = storeClient.SetSecret(ctx, v1alpha1.SecretRef{Key: "/app/db#password"}, []byte("latest"))
+	assert.ErrorIs(t, err, v1alpha1.ErrConflict)
+
+	value, err := storeClient.GetSecret(ctx, v1alpha1.SecretRef{Key: "/app/db#password"})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("concurrent"), value)
+
+	// Check-and-set requires KV version 2
+	err = (&Provider{}).Validate(v1alpha1.SecretStoreSpec{
+		Vault: &v1alpha1.VaultStore{
+			Address:     server.URL,
+			StorePath:   "secret",
+			AuthPath:    "userpass",
+			Token:       "root",
+			KVVersion:   kvVersion1,
+			CheckAndSet: true,
+		},
+	})
+	assert.Error(t, err)
+}
+
+func TestClientTLSAndNamespace(t *testing.T) {
+	ctx := context.Background()
+	server := &fakeVault{
+		kvVersion: kvVersion2,
+		namespace: "team-a",
+		secrets: map[string][]fakeVersion{
+			"db": {{data: map[string]interface{}{"password": "secret"}}},
+		},
+	}
+	server.Server = httptest.NewTLSServer(http.HandlerFunc(server.handle))
+	t.Cleanup(server.Close)
+
+	// Trust the server certificate
+	caCertPath := filepath.Join(t.TempDir(), "ca.crt")
+	caCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, os.WriteFile(caCertPath, caCert, 0o600))
+
+	storeClient := newTestClient(t, server, func(store *v1alpha1.VaultStore) {
+		store.Namespace = "team-a"
+		store.TLS = &v1alpha1.VaultTLS{CACertPath: caCertPath}
+	})
+
+	value, err := storeClient.GetSecret(ctx, v1alpha1.SecretRef{Key: "db/password"})
+	require.NoError(t, err)
+	assert.Equal(t, []byte("secret"), value)
+
+	// Client certificate requires a key
+	err = (&Provider{}).Validate(v1alpha1.SecretStoreSpec{
+		Vault: &v1alpha1.VaultStore{
+			Address:   server.URL,
+			StorePath: "secret",
+			AuthPath:  "userpass",
+			Token:     fakeToken,
+			TLS:       &v1alpha1.VaultTLS{ClientCertPath: "client.crt"},
+		},
+	})
+	assert.Error(t, err)
+}
+
+func TestClientIgnoresEnvironment(t *testing.T) {
+	ctx := context.Background()
+	server := &fakeVault{
+		kvVersion: kvVersion2,
+		secrets: map[string][]fakeVersion{
+			"db": {{data: map[string]interface{}{"password": "secret"}}},
+		},
+	}
+	server.Server = httptest.NewServer(http.HandlerFunc(server.handle))
+	t.Cleanup(server.Close)
+
+	// Valid TLS files, since the Vault API fails on unreadable ones
+	tlsServer := httptest.NewTLSServer(http.NotFoundHandler())
+	t.Cleanup(tlsServer.Close)
+	certPath := filepath.Join(t.TempDir(), "tls.crt")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsServer.Certificate().Raw})
+	require.NoError(t, os.WriteFile(certPath, cert, 0o600))
+	keyPath := filepath.Join(t.TempDir(), "tls.key")
+	keyBytes, err := x509.MarshalPKCS8PrivateKey(tlsServer.TLS.Certificates[0].PrivateKey)
+	require.NoError(t, err)
+	key := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyBytes})
+	require.NoError(t, os.WriteFile(keyPath, key, 0o600))
+
+	t.Setenv(vaultapi.EnvVaultAddress, "http://127.0.0.1:1")
+	t.Setenv(vaultapi.EnvVaultToken, "env-token")
+	t.Setenv(vaultapi.EnvVaultNamespace, "env-namespace")
+	t.Setenv(vaultapi.EnvVaultHeaders, `{"X-Env": "true"}`)
+	t.Setenv(vaultapi.EnvVaultCACert, certPath)
+	t.Setenv(vaultapi.EnvVaultClientCert, certPath)
+	t.Setenv(vaultapi.EnvVaultClientKey, keyPath)
+	t.Setenv("VAULT_CACERT_RELOAD", "false")
+
+	store := &v1alpha1.VaultStore{Address: server.URL}
+	config, err := newAPIConfig(store)
+	require.NoError(t, err)
+	transport := config.HttpClient.Transport.(*http.Transport)
+	assert.Nil(t, transport.TLSClientConfig.RootCAs)
+	assert.Nil(t, transport.TLSClientConfig.GetClientCertificate)
+
+	rawClient, err := newRawClient(store)
+	require.NoError(t, err)
+	assert.Equal(t, server.URL, rawClient.Address())
+	assert.Empty(t, rawClient.Token())
+	assert.Empty(t, rawClient.Namespace())
+	assert.Empty(t, rawClient.Headers().Get("X-Env"))
+
+	// Token and login clients only use store settings
+	storeClients := map[string]v1alpha1.StoreClient{
+		"Token": newTestClient(t, server, nil),
+		"Login": newTestClient(t, server, func(store *v1alpha1.VaultStore) {
+			store.AuthPath = ""
+			store.Token = ""
+			store.Auth = newTestAuth(t)
+		}),
+	}
+	for name, storeClient := range storeClients {
+		value, err := storeClient.GetSecret(ctx, v1alpha1.SecretRef{Key: "db/password"})
+		require.NoError(t, err, name)
+		assert.Equal(t, []byte("secret"), value, name)
+	}
+}
+
+func TestClientNoAPIRetries(t *testing.T) {
+	server := newFakeVault(t, kvVersion2)
+	storeClient := newTestClient(t, server, nil)
+
+	// Failed requests are left to the sync retry policy
+	server.unavailable = true
+	server.requests = 0
+	_, err := storeClient.GetSecret(context.Background(), v1alpha1.SecretRef{Key: "db/password"})
+	assert.ErrorIs(t, err, v1alpha1.ErrTransient)
+	assert.Equal(t, 1, server.requests)
+}
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantErr error
+	}{
+		{name: "Forbidden", err: &vaultapi.ResponseError{StatusCode: http.StatusForbidden}, wantErr: v1alpha1.ErrPermissionDenied},
+		{name: "Too many requests", err: &vaultapi.ResponseError{StatusCode: http.StatusTooManyRequests}, wantErr: v1alpha1.ErrTransient},
+		{name: "Unavailable", err: &vaultapi.ResponseError{StatusCode: http.StatusServiceUnavailable}, wantErr: v1alpha1.ErrTransient},
+		{name: "Bad request", err: &vaultapi.ResponseError{StatusCode: http.StatusBadRequest}},
+		{name: "Connection refused", err: &url.Error{Op: "Get", URL: "https://store", Err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}}, wantErr: v1alpha1.ErrTransient},
+		{name: "Timeout", err: &url.Error{Op: "Get", URL: "https://store", Err: os.ErrDeadlineExceeded}, wantErr: v1alpha1.ErrTransient},
+		{name: "Unknown host", err: &url.Error{Op: "Get", URL: "https://store", Err: &net.OpError{Op: "dial", Net: "tcp", Err: &net.DNSError{Err: "no such host", Name: "store", IsNotFound: true}}}},
+		{name: "Invalid certificate", err: &url.Error{Op: "Get", URL: "https://store", Err: &tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}}},
+		{name: "Cancelled", err: &url.Error{Op: "Get", URL: "https://store", Err: context.Canceled}},
+	}
+
+	for _, tt := range tests {
+		ttp := tt
+		t.Run(ttp.name, func(t *testing.T) {
+			err := classifyError(ttp.err)
+			assert.ErrorIs(t, err, ttp.err)
+			if ttp.wantErr == nil {
+				assert.False(t, errors.Is(err, v1alpha1.ErrPermissionDenied) || errors.Is(err, v1alpha1.ErrTransient))
+				return
+			}
+			assert.ErrorIs(t, err, ttp.wantErr)
+		})
+	}
+}
+
+// newTestClient creates a vault store client for the fake server.
+func newTestClient(t *testing.T, server *fakeVault, configure func(store *v1alpha1.VaultStore)) v1alpha1.StoreClient {
+	store := v1alpha1.SecretStoreSpec{
+		Vault: &v1alpha1.VaultStore{
+			Address:   server.URL,
+			StorePath: "secret",
+			AuthPath:  "userpass",
+			Token:     fakeToken,
+		},
+	}
+	if configure != nil {
+		configure(store.Vault)
+	}
+
+	provider := &Provider{}
+	require.NoError(t, provider.Validate(store))
+
+	storeClient, err := provider.NewClient(context.Background(), store)
+	require.NoError(t, err)
+
+	return storeClient
+}
+
+type fakeVersion struct {
+	data      map[string]interface{}
+	deleted   bool
+	destroyed bool
+}
+
+// fakeVault is a minimal Vault API stand-in serving a KV secrets engine mounted at "secret".
+type fakeVault struct {
+	*httptest.Server
+	mu        sync.Mutex
+	kvVersion int
+	namespace string
+	secrets   map[string][]fakeVersion
+
+	// Called before each write while holding the lock, e.g. to simulate concurrent writers.
+	beforeWrite func(secretPath string)
+
+	// Fails all requests with a temporary error if set.
+	unavailable bool
+	requests    int
+
+	logins []fakeLogin
+
+	// Token TTLs are in seconds, zero TTL never expires.
+	tokenTTL       int
+	tokenRenewable bool
+	renewTTL       int
+	renewals       int
+	revocations    int
+	revoked        []string
+}
+
+// fakeToken is the initial token accepted by fakeVault.
+// Tokens issued by logging in are accepted as well until revoked.
+const fakeToken = "root"
+
+type fakeLogin struct {
+	path string
+	data map[string]interface{}
+}
+
+func newFakeVault(t *testing.T, kvVersion int) *fakeVault {
+	fake := &fakeVault{
+		kvVersion: kvVersion,
+		secrets:   map[string][]fakeVersion{},
+	}
+	fake.Server = httptest.NewServer(http.HandlerFunc(fake.handle))
+	t.Cleanup(fake.Close)
+
+	return fake
+}
+
+// validToken checks if the token was issued by fakeVault and not revoked.
+func (v *fakeVault) validToken(token string) bool {
+	issued := token == fakeToken || strings.HasPrefix(token, fakeToken+"-login-")
+
+	return issued && !slices.Contains(v.revoked, token)
+}
+
+func (v *fakeVault) handle(w http.ResponseWriter, r *http.Request) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.requests++
+	if v.unavailable {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]interface{}{"errors": []string{"unavailable"}})
+		return
+	}
+
+	apiPath := strings.TrimPrefix(r.URL.Path, "/v1/")
+
+	// Mount info
+	if apiPath == "sys/internal/ui/mounts/secret" {
+		options := map[string]string{}
+		if v.kvVersion == kvVersion2 {
+			options["version"] = "2"
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"data": map[string]interface{}{"type": "kv", "options": options},
+		})
+		return
+	}
+
+	// Login, issues a new token
+	if strings.HasPrefix(apiPath, "auth/") && !strings.HasPrefix(apiPath, "auth/token/") {
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		v.logins = append(v.logins, fakeLogin{path: apiPath, data: body})
+		token := fmt.Sprintf("%s-login-%d", fakeToken, len(v.logins))
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"auth": map[string]interface{}{"client_token": token, "lease_duration": v.tokenTTL, "renewable": v.tokenRenewable},
+		})
+		return
+	}
+
+	if r.Header.Get("X-Vault-Namespace") != v.namespace {
+		writeJSON(w, http.StatusForbidden, map[string]interface{}{"errors": []string{"namespace not authorized"}})
+		return
+	}
+
+	token := r.Header.Get("X-Vault-Token")
+	if !v.validToken(token) {
+		writeJSON(w, http.StatusForbidden, map[string]interface{}{"errors": []string{"permission denied"}})
+		return
+	}
+
+	// Token lifecycle
+	switch apiPath {
+	case "auth/token/lookup-self":
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"data": map[string]interface{}{"ttl": v.tokenTTL, "creation_ttl": v.tokenTTL, "renewable": v.tokenRenewable},
+		})
+		return
+
+	case "auth/token/renew-self":
+		v.renewals++
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"auth": map[string]interface{}{"client_token": token, "lease_duration": v.renewTTL, "renewable": v.tokenRenewable},
+		})
+		return
+
+	case "auth/token/revoke-self":
+		v.revocations++
+		v.revoked = append(v.revoked, token)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// Resolve secret path, trailing slashes are stripped by the API client
+	secretPath := trimPathPrefix(apiPath, "secret")
+	if v.kvVersion == kvVersion2 {
+		switch {
+		case r.URL.Query().Get("list") == "true":
+			secretPath = trimPathPrefix(secretPath, "metadata")
+		case strings.HasPrefix(secretPath, "metadata/") && r.Method == http.MethodDelete:
+			delete(v.secrets, trimPathPrefix(secretPath, "metadata"))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		case strings.HasPrefix(secretPath, "metadata/"):
+			v.handleMetadata(w, trimPathPrefix(secretPath, "metadata"))
+			return
+		default:
+			secretPath = trimPathPrefix(secretPath, "data")
+		}
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Query().Get("list") == "true":
+		v.handleList(w, secretPath)
+
+	case r.Method == http.MethodGet:
+		v.handleRead(w, secretPath, r.URL.Query().Get("version"))
+
+	case r.Method == http.MethodDelete:
+		v.handleDelete(w, secretPath)
+
+	case r.Method == http.MethodPut || r.Method == http.MethodPost:
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if v.beforeWrite != nil {
+			v.beforeWrite(secretPath)
+		}
+		v.handleWrite(w, secretPath, body)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (v *fakeVault) handleList(w http.ResponseWriter, prefix string) {
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	keySet := map[string]struct{}{}
+	for secretPath := range v.secrets {
+		if !strings.HasPrefix(secretPath, prefix) {
+			continue
+		}
+
+		key := strings.TrimPrefix(secretPath, prefix)
+		if dir, _, found := strings.Cut(key, "/"); found {
+			key = dir + "/"
+		}
+		keySet[key] = struct{}{}
+	}
+
+	if len(keySet) == 0 {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+		return
+	}
+
+	keys := make([]string, 0, len(keySet))
+	for key := range keySet {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": map[string]interface{}{"keys": keys},
+	})
+}
+
+func (v *fakeVault) handleRead(w http.ResponseWriter, secretPath, version string) {
+	versions := v.secrets[secretPath]
+	index := len(versions)
+	if version != "" {
+		index, _ = strconv.Atoi(version)
+	}
+	if index < 1 || index > len(versions) {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+		return
+	}
+
+	current := versions[index-1]
+	if v.kvVersion == kvVersion1 {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"data": current.data})
+		return
+	}
+
+	metadata := map[string]interface{}{
+		"version":       index,
+		"deletion_time": "",
+		"destroyed":     current.destroyed,
+	}
+	if current.deleted || current.destroyed {
+		metadata["deletion_time"] = "2024-01-01T00:00:00Z"
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{
+			"data": map[string]interface{}{"data": nil, "metadata": metadata},
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": map[string]interface{}{"data": current.data, "metadata": metadata},
+	})
+}
+
+func (v *fakeVault) handleDelete(w http.ResponseWriter, secretPath string) {
+	versions := v.secrets[secretPath]
+	switch {
+	case v.kvVersion == kvVersion1:
+		delete(v.secrets, secretPath)
+	case len(versions) > 0:
+		versions[len(versions)-1].deleted = true
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (v *fakeVault) handleMetadata(w http.ResponseWriter, secretPath string) {
+	versions, ok := v.secrets[secretPath]
+	if !ok {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"errors": []string{}})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": map[string]interface{}{"current_version": len(versions)},
+	})
+}
+
+func (v *fakeVault) handleWrite(w http.ResponseWriter, secretPath string, body map[string]interface{}) {
+	if v.kvVersion == kvVersion1 {
+		v.secrets[secretPath] = []fakeVersion{{data: body}}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if options, ok := body["options"].(map[string]interface{}); ok && options["cas"] != nil {
+		if cas, _ := options["cas"].(float64); int(cas) != len(v.secrets[secretPath]) {
+			writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+				"errors": []string{"check-and-set parameter did not match the current version"},
+			})
+			return
+		}
+	}
+
+	data, _ := body["data"].(map[string]interface{})
+	v.secrets[secretPath] = append(v.secrets[secretPath], fakeVersion{data: data})
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"data": map[string]interface{}{"version": len(v.secrets[secretPath])},
+	})
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func trimPathPrefix(path, prefix string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(path, prefix), "/")
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
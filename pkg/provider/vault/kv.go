@@ -0,0 +1,256 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	vaultapi "github.com/hashicorp/vault/api"
+	"github.com/spf13/cast"
+
+	"github.com/bank-vaults/secret-sync/pkg/apis/v1alpha1"
+)
+
+const (
+	kvVersion1 = 1
+	kvVersion2 = 2
+
+	// maxListConcurrency defines how many API list requests can run at once during recursive queries.
+	maxListConcurrency = 8
+)
+
+// detectKVVersion returns the KV secrets engine version of the mount at given path.
+func detectKVVersion(ctx context.Context, apiClient *vaultapi.Client, mountPath string) (int, error) {
+	response, err := apiClient.Logical().ReadWithContext(ctx, "sys/internal/ui/mounts/"+mountPath)
+	if err != nil {
+		return 0, fmt.Errorf("api mount request failed: %w", err)
+	}
+
+	if response == nil || response.Data == nil {
+		return 0, fmt.Errorf("api mount request returned empty data for %s", mountPath)
+	}
+
+	// Only KV engines are supported, "generic" is the legacy name for KV v1
+	switch mountType := cast.ToString(response.Data["type"]); mountType {
+	case "kv", "generic":
+	default:
+		return 0, fmt.Errorf("mount %s has unsupported type %q", mountPath, mountType)
+	}
+
+	// Options are empty for KV v1
+	options, err := cast.ToStringMapStringE(response.Data["options"])
+	if err != nil || options["version"] == "" {
+		return kvVersion1, nil
+	}
+
+	version, err := cast.ToIntE(options["version"])
+	if err != nil {
+		return 0, fmt.Errorf("mount %s has invalid version %q", mountPath, options["version"])
+	}
+
+	return version, nil
+}
+
+// dataPath returns API path to read and write secret data.
+func (c *client) dataPath(path string) string {
+	if c.kvVersion == kvVersion1 {
+		return fmt.Sprintf("%s/%s", c.apiKeyPath, path)
+	}
+
+	return fmt.Sprintf("%s/data/%s", c.apiKeyPath, path)
+}
+
+// listPath returns API path to list secret keys.
+func (c *client) listPath(path string) string {
+	if c.kvVersion == kvVersion1 {
+		return fmt.Sprintf("%s/%s", c.apiKeyPath, path)
+	}
+
+	return fmt.Sprintf("%s/metadata/%s", c.apiKeyPath, path)
+}
+
+// deletePath returns API path to delete a secret based on the delete mode.
+func (c *client) deletePath(path string) string {
+	switch {
+	case c.kvVersion == kvVersion1:
+		return fmt.Sprintf("%s/%s", c.apiKeyPath, path)
+	case c.deleteMode == v1alpha1.VaultDeleteModeDestroy:
+		return fmt.Sprintf("%s/metadata/%s", c.apiKeyPath, path)
+	default:
+		return fmt.Sprintf("%s/data/%s", c.apiKeyPath, path)
+	}
+}
+
+// readSecret returns secret data and its current version (KV v2 only) from API.
+// Empty version reads the latest one.
+func (c *client) readSecret(ctx context.Context, secretPath, version string) (map[string]interface{}, int, error) {
+	// Get specific version if requested
+	var readParams map[string][]string
+	if version != "" {
+		if c.kvVersion == kvVersion1 {
+			return nil, 0, errors.New("versions are not supported for KV version 1")
+		}
+
+		readParams = map[string][]string{"version": {version}}
+	}
+
+	// Get secret from API
+	response, err := c.apiClient.RawClient().Logical().ReadWithDataWithContext(
+		ctx,
+		c.dataPath(secretPath),
+		readParams,
+	)
+	if err != nil {
+		return nil, 0, fmt.Errorf("api get request failed: %w", classifyError(err))
+	}
+
+	if response == nil || response.Data == nil {
+		// TODO: check if this is valid err return
+		return nil, 0, v1alpha1.ErrKeyNotFound
+	}
+
+	// Extract key value data
+	data, err := c.secretData(response)
+	if err != nil {
+		return nil, secretVersion(response), fmt.Errorf("api get request findind data: %w", err)
+	}
+
+	return data, secretVersion(response), nil
+}
+
+// currentVersion returns the current KV v2 secret version from its metadata, or 0 if the secret does not exist.
+func (c *client) currentVersion(ctx context.Context, secretPath string) (int, error) {
+	response, err := c.apiClient.RawClient().Logical().ReadWithContext(
+		ctx,
+		fmt.Sprintf("%s/metadata/%s", c.apiKeyPath, secretPath),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("api metadata request failed: %w", classifyError(err))
+	}
+
+	if response == nil || response.Data == nil {
+		return 0, nil
+	}
+
+	return cast.ToInt(response.Data["current_version"]), nil
+}
+
+// secretData extracts secret data from API read response.
+func (c *client) secretData(response *vaultapi.Secret) (map[string]interface{}, error) {
+	if c.kvVersion == kvVersion1 {
+		return response.Data, nil
+	}
+
+	secretData, ok := response.Data["data"]
+	if !ok || secretData == nil {
+		if isVersionDeleted(response) {
+			return nil, v1alpha1.ErrKeyVersionDeleted
+		}
+
+		return nil, errors.New("api get returned empty data")
+	}
+
+	return cast.ToStringMapE(secretData)
+}
+
+// isVersionDeleted checks if KV v2 read response points to a deleted or destroyed version.
+func isVersionDeleted(response *vaultapi.Secret) bool {
+	metadata, err := cast.ToStringMapE(response.Data["metadata"])
+	if err != nil {
+		return false
+	}
+
+	return cast.ToString(metadata["deletion_time"]) != "" || cast.ToBool(metadata["destroyed"])
+}
+
+// secretVersion extracts KV v2 secret version from API read response.
+func secretVersion(response *vaultapi.Secret) int {
+	metadata, err := cast.ToStringMapE(response.Data["metadata"])
+	if err != nil {
+		return 0
+	}
+
+	return cast.ToInt(metadata["version"])
+}
+
+// isCASMismatch checks if API write failed due to check-and-set version mismatch.
+func isCASMismatch(err error) bool {
+	var respErr *vaultapi.ResponseError
+	if !errors.As(err, &respErr) || respErr.StatusCode != http.StatusBadRequest {
+		return false
+	}
+
+	for _, msg := range respErr.Errors {
+		if strings.Contains(msg, "check-and-set") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// writeData wraps secret data into API write request.
+func (c *client) writeData(data map[string]interface{}) map[string]interface{} {
+	if c.kvVersion == kvVersion1 {
+		return data
+	}
+
+	return map[string]interface{}{
+		"data": data,
+	}
+}
+
+// classifyError marks API errors caused by missing permissions or likely temporary failures.
+func classifyError(err error) error {
+	var respErr *vaultapi.ResponseError
+	if errors.As(err, &respErr) {
+		switch {
+		case respErr.StatusCode == http.StatusForbidden:
+			return fmt.Errorf("%w: %w", v1alpha1.ErrPermissionDenied, err)
+		case respErr.StatusCode == http.StatusTooManyRequests, respErr.StatusCode >= http.StatusInternalServerError:
+			return fmt.Errorf("%w: %w", v1alpha1.ErrTransient, err)
+		}
+
+		return err
+	}
+
+	if isNetworkFailure(err) {
+		return fmt.Errorf("%w: %w", v1alpha1.ErrTransient, err)
+	}
+
+	return err
+}
+
+// isNetworkFailure checks if a request failed due to a timeout or a connection error.
+// Cancelled requests and failures such as invalid certificates or unknown hosts are not temporary.
+func isNetworkFailure(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+
+	var opErr *net.OpError
+	var netErr net.Error
+	return errors.As(err, &opErr) || (errors.As(err, &netErr) && netErr.Timeout())
+}
@@ -16,33 +16,146 @@ package vault
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
 
 	"github.com/bank-vaults/vault-sdk/vault"
+	"github.com/hashicorp/go-cleanhttp"
+	vaultapi "github.com/hashicorp/vault/api"
 
 	"github.com/bank-vaults/secret-sync/pkg/apis/v1alpha1"
 )
 
 type Provider struct{}
 
-func (p *Provider) NewClient(_ context.Context, backend v1alpha1.SecretStoreSpec) (v1alpha1.StoreClient, error) {
-	apiClient, err := vault.NewClientWithOptions(
-		vault.ClientURL(backend.Vault.Address),
-		vault.ClientRole(backend.Vault.Role),
-		vault.ClientAuthPath(backend.Vault.AuthPath),
-		vault.ClientTokenPath(backend.Vault.TokenPath),
-		vault.ClientToken(backend.Vault.Token))
+func (p *Provider) NewClient(ctx context.Context, backend v1alpha1.SecretStoreSpec) (v1alpha1.StoreClient, error) {
+	apiClient, err := newAPIClient(ctx, backend.Vault)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create vault client: %w", err)
 	}
 
+	// Keep token valid for long-running syncs
+	tokens := newTokenManager(apiClient.RawClient(), backend.Vault.Auth)
+	if err := tokens.start(ctx); err != nil {
+		slog.WarnContext(ctx, fmt.Errorf("vault token will not be refreshed: %w", err).Error())
+	}
+
+	kvVersion := backend.Vault.KVVersion
+	if kvVersion == 0 {
+		kvVersion, err = detectKVVersion(ctx, apiClient.RawClient(), backend.Vault.StorePath)
+		if err != nil {
+			_ = tokens.Close()
+			apiClient.Close()
+			return nil, fmt.Errorf("failed to detect kv version: %w", err)
+		}
+	}
+
+	if backend.Vault.CheckAndSet && kvVersion != kvVersion2 {
+		_ = tokens.Close()
+		apiClient.Close()
+		return nil, errors.New("check-and-set requires KV version 2")
+	}
+
 	return &client{
-		apiClient:  apiClient,
-		apiKeyPath: backend.Vault.StorePath,
+		apiClient:   apiClient,
+		tokens:      tokens,
+		apiKeyPath:  backend.Vault.StorePath,
+		kvVersion:   kvVersion,
+		keyMode:     backend.Vault.GetKeyMode(),
+		writeMode:   backend.Vault.GetWriteMode(),
+		deleteMode:  backend.Vault.GetDeleteMode(),
+		checkAndSet: backend.Vault.CheckAndSet,
 	}, nil
 }
 
+// newAPIClient creates an authenticated Vault API client.
+func newAPIClient(ctx context.Context, store *v1alpha1.VaultStore) (*vault.Client, error) {
+	rawClient, err := newRawClient(store)
+	if err != nil {
+		return nil, err
+	}
+
+	if store.Auth == nil {
+		return vault.NewClientFromRawClientWithContext(
+			ctx,
+			rawClient,
+			vault.ClientRole(store.Role),
+			vault.ClientAuthPath(store.AuthPath),
+			vault.ClientTokenPath(store.TokenPath),
+			vault.ClientToken(store.Token))
+	}
+
+	// Log in using the configured auth method
+	auth, err := login(ctx, rawClient, store.Auth)
+	if err != nil {
+		return nil, err
+	}
+	rawClient.SetToken(auth.ClientToken)
+
+	return vault.NewClientFromRawClientWithContext(ctx, rawClient)
+}
+
+// newRawClient creates an unauthenticated Vault API client for a store.
+// The token, namespace and headers that the Vault API reads from VAULT_*
+// environment variables are dropped so that only store settings apply.
+func newRawClient(store *v1alpha1.VaultStore) (*vaultapi.Client, error) {
+	config, err := newAPIConfig(store)
+	if err != nil {
+		return nil, err
+	}
+
+	rawClient, err := vaultapi.NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+	rawClient.SetHeaders(http.Header{vaultapi.RequestHeaderName: []string{"true"}})
+	rawClient.ClearToken()
+	if store.Namespace != "" {
+		rawClient.SetNamespace(store.Namespace)
+	}
+
+	return rawClient, nil
+}
+
+// newAPIConfig returns Vault API config for a store.
+// Unlike vaultapi.DefaultConfig, it does not read VAULT_* environment variables.
+func newAPIConfig(store *v1alpha1.VaultStore) (*vaultapi.Config, error) {
+	config := &vaultapi.Config{
+		Address:    store.Address,
+		HttpClient: cleanhttp.DefaultPooledClient(),
+		Timeout:    60 * time.Second,
+		MaxRetries: 0, // requests are retried by the sync retry policy only
+	}
+
+	transport := config.HttpClient.Transport.(*http.Transport)
+	transport.TLSHandshakeTimeout = 10 * time.Second
+	transport.TLSClientConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+
+	// Redirects are handled by the Vault API client
+	config.HttpClient.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	if store.TLS != nil {
+		err := config.ConfigureTLS(&vaultapi.TLSConfig{
+			CACert:        store.TLS.CACertPath,
+			ClientCert:    store.TLS.ClientCertPath,
+			ClientKey:     store.TLS.ClientKeyPath,
+			TLSServerName: store.TLS.ServerName,
+			Insecure:      store.TLS.Insecure,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure tls: %w", err)
+		}
+	}
+
+	return config, nil
+}
+
 func (p *Provider) Validate(backend v1alpha1.SecretStoreSpec) error {
 	if backend.Vault == nil {
 		return errors.New("empty Vault config")
@@ -53,11 +166,39 @@ func (p *Provider) Validate(backend v1alpha1.SecretStoreSpec) error {
 	if backend.Vault.StorePath == "" {
 		return errors.New("empty .Vault.StorePath")
 	}
-	if backend.Vault.AuthPath == "" {
+	if backend.Vault.Auth != nil {
+		if backend.Vault.Role != "" || backend.Vault.AuthPath != "" || backend.Vault.TokenPath != "" || backend.Vault.Token != "" {
+			return errors.New("cannot use .Vault.Auth with .Vault.Role, .Vault.AuthPath, .Vault.TokenPath or .Vault.Token")
+		}
+		if err := validateAuth(backend.Vault.Auth); err != nil {
+			return err
+		}
+	} else if backend.Vault.AuthPath == "" {
 		return errors.New("empty .Vault.AuthPath")
 	}
-	if backend.Vault.Token == "" {
-		return errors.New("empty .Vault.Token")
+	if tls := backend.Vault.TLS; tls != nil && (tls.ClientCertPath == "") != (tls.ClientKeyPath == "") {
+		return errors.New("both .Vault.TLS.ClientCertPath and .Vault.TLS.ClientKeyPath must be specified")
+	}
+	if backend.Vault.KVVersion != 0 && backend.Vault.KVVersion != kvVersion1 && backend.Vault.KVVersion != kvVersion2 {
+		return fmt.Errorf("unsupported .Vault.KVVersion %d", backend.Vault.KVVersion)
+	}
+	switch backend.Vault.GetKeyMode() {
+	case v1alpha1.VaultKeyModeField, v1alpha1.VaultKeyModeSecret:
+	default:
+		return fmt.Errorf("unsupported .Vault.KeyMode %q", backend.Vault.KeyMode)
+	}
+	if backend.Vault.CheckAndSet && backend.Vault.KVVersion == kvVersion1 {
+		return errors.New("invalid .Vault.CheckAndSet, requires .Vault.KVVersion 2")
+	}
+	switch backend.Vault.GetWriteMode() {
+	case v1alpha1.VaultWriteModeReplace, v1alpha1.VaultWriteModeMerge:
+	default:
+		return fmt.Errorf("unsupported .Vault.WriteMode %q", backend.Vault.WriteMode)
+	}
+	switch backend.Vault.GetDeleteMode() {
+	case v1alpha1.VaultDeleteModeSoft, v1alpha1.VaultDeleteModeDestroy:
+	default:
+		return fmt.Errorf("unsupported .Vault.DeleteMode %q", backend.Vault.DeleteMode)
 	}
 
 	return nil
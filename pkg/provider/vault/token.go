@@ -0,0 +1,272 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	vaultapi "github.com/hashicorp/vault/api"
+	"github.com/spf13/cast"
+
+	"github.com/bank-vaults/secret-sync/pkg/apis/v1alpha1"
+)
+
+const (
+	// minRefreshInterval limits how often a token with very short TTL is refreshed.
+	minRefreshInterval = time.Second
+
+	// refreshRetryInterval defines how long to wait before retrying a failed refresh.
+	refreshRetryInterval = 5 * time.Second
+
+	// revokeTimeout limits how long token revocation can take on shutdown.
+	revokeTimeout = 10 * time.Second
+)
+
+var errNotRefreshable = errors.New("token is not renewable and no auth method is configured")
+
+// tokenManager keeps the client token valid for long-running syncs.
+// Tokens are renewed before they expire, and if the token cannot be renewed,
+// a new one is obtained by logging in again. Tokens obtained by logging in
+// are revoked when replaced and on close.
+type tokenManager struct {
+	apiClient *vaultapi.Client
+	auth      *v1alpha1.VaultAuth // nil if the token was not obtained by logging in
+
+	mu          sync.Mutex
+	renewable   bool
+	creationTTL time.Duration
+	stop        context.CancelFunc
+	done        chan struct{}
+}
+
+func newTokenManager(apiClient *vaultapi.Client, auth *v1alpha1.VaultAuth) *tokenManager {
+	return &tokenManager{
+		apiClient: apiClient,
+		auth:      auth,
+	}
+}
+
+// start looks up the current token and refreshes it in the background until closed.
+// Tokens which never expire are not refreshed. Returns an error if the token
+// expires but can neither be renewed nor replaced by logging in again.
+func (m *tokenManager) start(ctx context.Context) error {
+	ttl, err := m.lookup(ctx)
+	if err != nil {
+		return err
+	}
+
+	if ttl == 0 {
+		return nil
+	}
+
+	if !m.refreshable() {
+		return errNotRefreshable
+	}
+
+	refreshCtx, stop := context.WithCancel(context.WithoutCancel(ctx))
+	m.stop = stop
+	m.done = make(chan struct{})
+	go m.run(refreshCtx, ttl)
+
+	return nil
+}
+
+// lookup reads the current token TTL and renewability.
+func (m *tokenManager) lookup(ctx context.Context) (time.Duration, error) {
+	response, err := m.apiClient.Auth().Token().LookupSelfWithContext(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("api token lookup request failed: %w", err)
+	}
+
+	if response == nil || response.Data == nil {
+		return 0, errors.New("api token lookup returned empty data")
+	}
+
+	ttl, err := response.TokenTTL()
+	if err != nil {
+		return 0, fmt.Errorf("api token lookup returned invalid ttl: %w", err)
+	}
+
+	renewable, _ := response.TokenIsRenewable()
+
+	m.mu.Lock()
+	m.renewable = renewable
+	m.creationTTL = time.Duration(cast.ToInt64(response.Data["creation_ttl"])) * time.Second
+	m.mu.Unlock()
+
+	return ttl, nil
+}
+
+// run refreshes the token every time two thirds of its TTL have passed.
+func (m *tokenManager) run(ctx context.Context, ttl time.Duration) {
+	defer close(m.done)
+
+	wait := refreshInterval(ttl)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		ttl, err := m.refresh(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			slog.ErrorContext(ctx, fmt.Errorf("failed to refresh vault token: %w", err).Error())
+			wait = refreshRetryInterval
+			continue
+		}
+
+		slog.DebugContext(ctx, "Refreshed vault token", slog.Any("ttl", ttl))
+		if !m.refreshable() {
+			slog.WarnContext(ctx, fmt.Errorf("vault token will not be refreshed: %w", errNotRefreshable).Error())
+			return
+		}
+		wait = refreshInterval(ttl)
+	}
+}
+
+// refreshable checks if the token can be renewed or replaced by logging in again.
+func (m *tokenManager) refreshable() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.renewable || m.auth != nil
+}
+
+// refresh renews the token, or logs in again if the token cannot be renewed
+// or its renewal is capped by max TTL. Returns the new token TTL.
+func (m *tokenManager) refresh(ctx context.Context) (time.Duration, error) {
+	m.mu.Lock()
+	renewable, creationTTL := m.renewable, m.creationTTL
+	m.mu.Unlock()
+
+	var renewErr error
+	if renewable {
+		ttl, err := m.renew(ctx)
+		switch {
+		case err != nil:
+			renewErr = err
+		case m.auth == nil || ttl >= creationTTL/2:
+			return ttl, nil
+		default:
+			// Token is close to its max TTL, log in again while it is still valid
+		}
+	}
+
+	if m.auth == nil {
+		if renewErr != nil {
+			return 0, renewErr
+		}
+
+		return 0, errNotRefreshable
+	}
+
+	return m.relogin(ctx)
+}
+
+// renew extends the current token TTL.
+func (m *tokenManager) renew(ctx context.Context) (time.Duration, error) {
+	response, err := m.apiClient.Auth().Token().RenewSelfWithContext(ctx, 0)
+	if err != nil {
+		return 0, fmt.Errorf("api token renew request failed: %w", err)
+	}
+
+	if response == nil || response.Auth == nil {
+		return 0, errors.New("api token renew returned empty auth")
+	}
+
+	m.mu.Lock()
+	m.renewable = response.Auth.Renewable
+	m.mu.Unlock()
+
+	return time.Duration(response.Auth.LeaseDuration) * time.Second, nil
+}
+
+// relogin obtains a new token using the configured auth method.
+func (m *tokenManager) relogin(ctx context.Context) (time.Duration, error) {
+	auth, err := login(ctx, m.apiClient, m.auth)
+	if err != nil {
+		return 0, err
+	}
+
+	ttl := time.Duration(auth.LeaseDuration) * time.Second
+
+	m.mu.Lock()
+	oldToken := m.apiClient.Token()
+	m.apiClient.SetToken(auth.ClientToken)
+	m.renewable = auth.Renewable
+	m.creationTTL = ttl
+	m.mu.Unlock()
+
+	// Previous token is no longer used, do not leave it valid until it expires
+	if oldToken != "" && oldToken != auth.ClientToken {
+		if err := m.revoke(ctx, oldToken); err != nil {
+			slog.WarnContext(ctx, fmt.Errorf("failed to revoke previous vault token: %w", err).Error())
+		}
+	}
+
+	return ttl, nil
+}
+
+// revoke revokes the given token using a copy of the API client.
+func (m *tokenManager) revoke(ctx context.Context, token string) error {
+	apiClient, err := m.apiClient.Clone()
+	if err != nil {
+		return err
+	}
+	apiClient.SetHeaders(m.apiClient.Headers())
+	apiClient.SetToken(token)
+
+	if err := apiClient.Auth().Token().RevokeSelfWithContext(ctx, ""); err != nil {
+		return fmt.Errorf("api token revoke request failed: %w", err)
+	}
+
+	return nil
+}
+
+// Close stops token refresh and revokes the token if it was obtained by logging in.
+func (m *tokenManager) Close() error {
+	if m.stop != nil {
+		m.stop()
+		<-m.done
+	}
+
+	if m.auth == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), revokeTimeout)
+	defer cancel()
+
+	if err := m.apiClient.Auth().Token().RevokeSelfWithContext(ctx, ""); err != nil {
+		return fmt.Errorf("api token revoke request failed: %w", err)
+	}
+
+	return nil
+}
+
+// refreshInterval returns how long to wait before refreshing a token with given TTL.
+func refreshInterval(ttl time.Duration) time.Duration {
+	return max(ttl*2/3, minRefreshInterval)
+}
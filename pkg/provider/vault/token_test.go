@@ -0,0 +1,179 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vault
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	vaultapi "github.com/hashicorp/vault/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bank-vaults/secret-sync/pkg/apis/v1alpha1"
+)
+
+func TestTokenManagerRefresh(t *testing.T) {
+	tests := []struct {
+		name         string
+		renewable    bool
+		renewTTL     int
+		withAuth     bool
+		wantTTL      time.Duration
+		wantRenewals int
+		wantLogins   int
+		wantErr      bool
+		wantRevoked  []string
+	}{
+		{
+			name:         "Renew",
+			renewable:    true,
+			renewTTL:     3600,
+			wantTTL:      time.Hour,
+			wantRenewals: 1,
+		},
+		{
+			name:         "Renew capped by max TTL without auth",
+			renewable:    true,
+			renewTTL:     60,
+			wantTTL:      time.Minute,
+			wantRenewals: 1,
+		},
+		{
+			name:         "Renew capped by max TTL with auth",
+			renewable:    true,
+			renewTTL:     60,
+			withAuth:     true,
+			wantTTL:      time.Hour,
+			wantRenewals: 1,
+			wantLogins:   1,
+			wantRevoked:  []string{fakeToken, fakeToken + "-login-1"},
+		},
+		{
+			name:        "Not renewable with auth",
+			withAuth:    true,
+			wantTTL:     time.Hour,
+			wantLogins:  1,
+			wantRevoked: []string{fakeToken, fakeToken + "-login-1"},
+		},
+		{
+			name:    "Not renewable without auth",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		ttp := tt
+		t.Run(ttp.name, func(t *testing.T) {
+			ctx := context.Background()
+			server := newFakeVault(t, kvVersion2)
+			server.tokenTTL = 3600
+			server.tokenRenewable = ttp.renewable
+			server.renewTTL = ttp.renewTTL
+
+			var auth *v1alpha1.VaultAuth
+			if ttp.withAuth {
+				auth = newTestAuth(t)
+			}
+			tokens := newTestTokenManager(t, server, auth)
+			_, err := tokens.lookup(ctx)
+			require.NoError(t, err)
+
+			ttl, err := tokens.refresh(ctx)
+			if ttp.wantErr {
+				assert.Error(t, err)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, ttp.wantTTL, ttl)
+			}
+			assert.Equal(t, ttp.wantRenewals, server.renewals)
+			assert.Len(t, server.logins, ttp.wantLogins)
+
+			// Only tokens obtained by logging in are revoked, replaced ones first
+			require.NoError(t, tokens.Close())
+			assert.Equal(t, ttp.wantRevoked, server.revoked)
+		})
+	}
+}
+
+func TestTokenManagerBackground(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeVault(t, kvVersion2)
+	server.tokenTTL = 1
+	server.tokenRenewable = true
+	server.renewTTL = 1
+
+	tokens := newTestTokenManager(t, server, nil)
+	require.NoError(t, tokens.start(ctx))
+
+	assert.Eventually(t, func() bool {
+		server.mu.Lock()
+		defer server.mu.Unlock()
+
+		return server.renewals > 0
+	}, 5*time.Second, 50*time.Millisecond)
+
+	require.NoError(t, tokens.Close())
+}
+
+func TestTokenManagerNonExpiring(t *testing.T) {
+	server := newFakeVault(t, kvVersion2)
+
+	tokens := newTestTokenManager(t, server, nil)
+	require.NoError(t, tokens.start(context.Background()))
+	assert.Nil(t, tokens.done, "non-expiring tokens are not refreshed")
+
+	require.NoError(t, tokens.Close())
+	assert.Zero(t, server.revocations)
+}
+
+func TestTokenManagerNotRefreshable(t *testing.T) {
+	server := newFakeVault(t, kvVersion2)
+	server.tokenTTL = 3600
+
+	// Static tokens which cannot be renewed are left to expire
+	tokens := newTestTokenManager(t, server, nil)
+	require.ErrorIs(t, tokens.start(context.Background()), errNotRefreshable)
+	assert.Nil(t, tokens.done, "tokens which cannot be renewed are not refreshed")
+
+	require.NoError(t, tokens.Close())
+	assert.Zero(t, server.renewals)
+	assert.Zero(t, server.revocations)
+}
+
+// newTestTokenManager creates a token manager for an API client authenticated to the fake server.
+func newTestTokenManager(t *testing.T, server *fakeVault, auth *v1alpha1.VaultAuth) *tokenManager {
+	config := vaultapi.DefaultConfig()
+	config.Address = server.URL
+
+	apiClient, err := vaultapi.NewClient(config)
+	require.NoError(t, err)
+	apiClient.SetToken(fakeToken)
+
+	return newTokenManager(apiClient, auth)
+}
+
+// newTestAuth returns userpass auth with password stored in a temporary file.
+func newTestAuth(t *testing.T) *v1alpha1.VaultAuth {
+	passwordPath := filepath.Join(t.TempDir(), "password")
+	require.NoError(t, os.WriteFile(passwordPath, []byte("password"), 0o600))
+
+	return &v1alpha1.VaultAuth{
+		UserPass: &v1alpha1.VaultUserPassAuth{Username: "admin", PasswordPath: passwordPath},
+	}
+}
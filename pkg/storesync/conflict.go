@@ -0,0 +1,123 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storesync
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+
+	"github.com/bank-vaults/secret-sync/pkg/apis/v1alpha1"
+)
+
+// Collision describes a target key which was scheduled for sync by multiple actions.
+type Collision struct {
+	Key     string                  `json:"key"`
+	Actions []int                   `json:"actions"` // ids of colliding actions, in order
+	Policy  v1alpha1.ConflictPolicy `json:"policy"`  // policy used to resolve the last collision
+	Winner  int                     `json:"winner"`  // id of the action whose value is synced, -1 if values were merged
+}
+
+// equal checks if collisions were resolved the same way.
+func (c Collision) equal(other Collision) bool {
+	return c.Key == other.Key && c.Policy == other.Policy && c.Winner == other.Winner && slices.Equal(c.Actions, other.Actions)
+}
+
+// validateConflictPolicy checks if a conflict policy is supported.
+func validateConflictPolicy(policy v1alpha1.ConflictPolicy) error {
+	switch policy {
+	case "", v1alpha1.ConflictPolicyFail, v1alpha1.ConflictPolicyFirstWins,
+		v1alpha1.ConflictPolicyLastWinsByOrder, v1alpha1.ConflictPolicyMerge:
+		return nil
+	default:
+		return fmt.Errorf("unsupported conflict policy %q", policy)
+	}
+}
+
+// resolveConflict returns the request to sync for a key scheduled by both existing
+// and a later listed request, based on the given policy.
+func resolveConflict(policy v1alpha1.ConflictPolicy, existing, request syncRequest) (syncRequest, error) {
+	switch policy {
+	case v1alpha1.ConflictPolicyFirstWins:
+		return existing, nil
+
+	case v1alpha1.ConflictPolicyLastWinsByOrder:
+		return request, nil
+
+	case v1alpha1.ConflictPolicyMerge:
+		data, err := mergeData(existing.Data, request.Data)
+		if err != nil {
+			return syncRequest{}, fmt.Errorf("failed to merge values: %w", err)
+		}
+		request.Data = data
+		request.SourceRefs = slices.Concat(existing.SourceRefs, request.SourceRefs)
+		return request, nil
+
+	default:
+		return syncRequest{}, errors.New("key was scheduled for sync more than once")
+	}
+}
+
+// mergeData merges two JSON maps, with keys from the latter taking precedence.
+func mergeData(existing, data []byte) ([]byte, error) {
+	existingMap, err := decodeMap(existing)
+	if err != nil {
+		return nil, err
+	}
+	dataMap, err := decodeMap(data)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, value := range dataMap {
+		existingMap[key] = value
+	}
+
+	return json.Marshal(existingMap)
+}
+
+// decodeMap decodes a JSON map. Numbers are kept as written, e.g. large integers
+// are not rounded to float64.
+func decodeMap(data []byte) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var result map[string]interface{}
+	if err := decoder.Decode(&result); err != nil || result == nil {
+		return nil, errors.New("value is not a JSON map")
+	}
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("value is not a JSON map")
+	}
+
+	return result, nil
+}
+
+// sortedCollisions returns collisions sorted by key.
+func sortedCollisions(collisions map[string]*Collision) []Collision {
+	result := make([]Collision, 0, len(collisions))
+	for _, collision := range collisions {
+		result = append(result, *collision)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Key < result[j].Key
+	})
+
+	return result
+}
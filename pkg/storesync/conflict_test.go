@@ -0,0 +1,70 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storesync
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeData(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+		data     string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "Override keys",
+			existing: `{"user":"app","pass":"app"}`,
+			data:     `{"pass":"override"}`,
+			want:     `{"pass":"override","user":"app"}`,
+		},
+		{
+			name:     "Keep numbers",
+			existing: `{"id":12345678901234567890,"ratio":0.10}`,
+			data:     `{"port":5432}`,
+			want:     `{"id":12345678901234567890,"port":5432,"ratio":0.10}`,
+		},
+		{
+			name:     "Not a map",
+			existing: `{"user":"app"}`,
+			data:     `["pass"]`,
+			wantErr:  true,
+		},
+		{
+			name:     "Trailing data",
+			existing: `{"user":"app"} {"pass":"app"}`,
+			data:     `{"pass":"override"}`,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		ttp := tt
+		t.Run(ttp.name, func(t *testing.T) {
+			merged, err := mergeData([]byte(ttp.existing), []byte(ttp.data))
+			if ttp.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, ttp.want, string(merged))
+		})
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storesync
+
+import (
+	"context"
+	"errors"
+
+	"golang.org/x/time/rate"
+
+	"github.com/bank-vaults/secret-sync/pkg/apis/v1alpha1"
+)
+
+// limiter bounds the number of concurrent requests and their rate.
+type limiter struct {
+	slots chan struct{} // nil if concurrency is unlimited
+	rate  *rate.Limiter // nil if rate is unlimited
+}
+
+// validateLimits checks if store limits are valid.
+func validateLimits(limits *v1alpha1.StoreLimits) error {
+	if limits == nil {
+		return nil
+	}
+	if limits.MaxConcurrency < 0 {
+		return errors.New("negative 'maxConcurrency'")
+	}
+	if limits.RequestsPerSecond < 0 {
+		return errors.New("negative 'requestsPerSecond'")
+	}
+
+	return nil
+}
+
+// newLimiter returns a limiter for given limits, or nil if nothing is limited.
+func newLimiter(limits *v1alpha1.StoreLimits) *limiter {
+	if limits == nil || (limits.MaxConcurrency == 0 && limits.RequestsPerSecond == 0) {
+		return nil
+	}
+
+	l := &limiter{}
+	if limits.MaxConcurrency > 0 {
+		l.slots = make(chan struct{}, limits.MaxConcurrency)
+	}
+	if limits.RequestsPerSecond > 0 {
+		l.rate = rate.NewLimiter(rate.Limit(limits.RequestsPerSecond), 1)
+	}
+
+	return l
+}
+
+// acquire waits until a request can be made. Returned func must be called once the request completes.
+func (l *limiter) acquire(ctx context.Context) (func(), error) {
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	release := func() {
+		if l.slots != nil {
+			<-l.slots
+		}
+	}
+
+	if l.rate != nil {
+		if err := l.rate.Wait(ctx); err != nil {
+			release()
+			return nil, err
+		}
+	}
+
+	return release, nil
+}
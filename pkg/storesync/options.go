@@ -0,0 +1,104 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storesync
+
+import (
+	"time"
+
+	"github.com/bank-vaults/secret-sync/pkg/apis/v1alpha1"
+)
+
+// DefaultWriteGracePeriod is how long writes in flight can continue once a sync is cancelled.
+const DefaultWriteGracePeriod = 30 * time.Second
+
+// Option configures Sync and Plan.
+type Option func(*options)
+
+type options struct {
+	conflictPolicy v1alpha1.ConflictPolicy
+	failurePolicy  v1alpha1.FailurePolicy
+	sourceLimits   *v1alpha1.StoreLimits
+	targetLimits   *v1alpha1.StoreLimits
+	retryPolicy    *v1alpha1.RetryPolicy
+	gracePeriod    time.Duration
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{
+		conflictPolicy: v1alpha1.ConflictPolicyFail,
+		failurePolicy:  v1alpha1.FailurePolicyContinue,
+		gracePeriod:    DefaultWriteGracePeriod,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// WithConflictPolicy sets how keys scheduled for sync by multiple actions are handled
+// for actions which do not define their own policy.
+// Defaults to v1alpha1.ConflictPolicyFail.
+func WithConflictPolicy(policy v1alpha1.ConflictPolicy) Option {
+	return func(o *options) {
+		if policy != "" {
+			o.conflictPolicy = policy
+		}
+	}
+}
+
+// WithFailurePolicy sets how failures to fetch or sync keys are handled.
+// Defaults to v1alpha1.FailurePolicyContinue.
+func WithFailurePolicy(policy v1alpha1.FailurePolicy) Option {
+	return func(o *options) {
+		if policy != "" {
+			o.failurePolicy = policy
+		}
+	}
+}
+
+// WithSourceLimits limits requests made to the source store.
+func WithSourceLimits(limits *v1alpha1.StoreLimits) Option {
+	return func(o *options) {
+		o.sourceLimits = limits
+	}
+}
+
+// WithTargetLimits limits requests made to the target store.
+// Writes are also scheduled no faster than allowed by max concurrency.
+func WithTargetLimits(limits *v1alpha1.StoreLimits) Option {
+	return func(o *options) {
+		o.targetLimits = limits
+	}
+}
+
+// WithRetryPolicy retries source and target requests which failed due to temporary errors,
+// i.e. errors wrapping v1alpha1.ErrTransient.
+func WithRetryPolicy(policy *v1alpha1.RetryPolicy) Option {
+	return func(o *options) {
+		o.retryPolicy = policy
+	}
+}
+
+// WithWriteGracePeriod sets how long writes in flight can continue once the sync is cancelled.
+// Writes still running after the grace period are cancelled.
+// Defaults to DefaultWriteGracePeriod.
+func WithWriteGracePeriod(period time.Duration) Option {
+	return func(o *options) {
+		if period > 0 {
+			o.gracePeriod = period
+		}
+	}
+}
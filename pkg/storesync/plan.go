@@ -0,0 +1,281 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storesync
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bank-vaults/secret-sync/pkg/apis/v1alpha1"
+)
+
+// PlanAction defines what Sync would do with a target key.
+type PlanAction string
+
+const (
+	PlanActionCreate    PlanAction = "create"
+	PlanActionUpdate    PlanAction = "update"
+	PlanActionUnchanged PlanAction = "unchanged"
+	PlanActionDelete    PlanAction = "delete"
+	PlanActionUnknown   PlanAction = "unknown" // key cannot be planned, see KeyPlan.Error
+)
+
+// PlanResult defines response data returned by Plan.
+type PlanResult struct {
+	Keys       []KeyPlan    //  planned changes, sorted by key
+	Collisions []Collision  //  keys scheduled for sync by multiple actions
+	Failed     []int        //  ids of actions which could not be fetched from source
+	Create     uint32       //  number of keys which would be created
+	Update     uint32       //  number of keys which would be updated
+	Unchanged  uint32       //  number of keys which already have the desired value
+	Delete     uint32       //  number of keys which would be pruned
+	Unknown    uint32       //  number of keys which could not be planned
+	PlannedAt  time.Time    //  completion timestamp
+	Targets    []TargetPlan //  plan of each target, only set by PlanTargets
+}
+
+// TargetPlan defines PlanResult for a single target.
+type TargetPlan struct {
+	Name string
+	PlanResult
+}
+
+// KeyPlan defines a planned change for a single target key.
+type KeyPlan struct {
+	Key       string
+	Target    string // name of the target, empty if there is only one target
+	RequestID int
+	Action    PlanAction
+	Current   *ValueSummary // nil if key does not exist in target
+	Desired   *ValueSummary // nil if key would be deleted
+	Error     string        // reason why key could not be planned
+}
+
+// ValueSummary describes a secret value without exposing it.
+// Hashes are keyed with a random per-plan secret, so they can only be
+// compared within the same plan and cannot be used to guess values.
+type ValueSummary struct {
+	Hash   string
+	Length int
+}
+
+// Plan returns changes Sync would make to target without writing anything.
+func Plan(ctx context.Context,
+	source v1alpha1.StoreReader,
+	target v1alpha1.StoreClient,
+	actions []v1alpha1.SyncAction,
+	opts ...Option,
+) (*PlanResult, error) {
+	plan, err := PlanTargets(ctx, source, []Target{{Store: target}}, actions, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &plan.Targets[0].PlanResult, nil
+}
+
+// PlanTargets returns changes SyncTargets would make to each target without writing anything.
+// Returned PlanResult sums up all targets and lists the plan of each target in PlanResult.Targets.
+// Value hashes can be compared across targets. Targets must support reading.
+func PlanTargets(ctx context.Context,
+	source v1alpha1.StoreReader,
+	targets []Target,
+	actions []v1alpha1.SyncAction,
+	opts ...Option,
+) (*PlanResult, error) {
+	for _, target := range targets {
+		if _, ok := target.Store.(v1alpha1.StoreReader); target.Store != nil && !ok {
+			return nil, fmt.Errorf("target %q does not support reading", target.Name)
+		}
+	}
+
+	fetched, err := fetchTargets(ctx, source, targets, actions, newOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	summarize := func(value []byte) *ValueSummary {
+		hash := fetched.hashValue(value)
+		if len(hash) > 16 {
+			hash = hash[:16]
+		}
+
+		return &ValueSummary{
+			Hash:   hash,
+			Length: len(value),
+		}
+	}
+
+	plans := make([]TargetPlan, len(fetched.targets))
+	for i, target := range fetched.targets {
+		plans[i] = TargetPlan{
+			Name:       target.name,
+			PlanResult: *planTarget(ctx, target, summarize),
+		}
+	}
+
+	return combinePlans(plans, fetched.failedActions), nil
+}
+
+// planTarget compares desired values with current values of a single target.
+func planTarget(ctx context.Context, target *targetRequests, summarize func([]byte) *ValueSummary) *PlanResult {
+	store := target.store.(v1alpha1.StoreClient)
+	syncRequests, pruneRequests := target.syncRequests, target.pruneRequests
+
+	// Compare desired values with current target values.
+	// Read each key in a separate goroutine.
+	var planMu sync.Mutex
+	var planWg sync.WaitGroup
+	var keys []KeyPlan
+	for ref, req := range syncRequests {
+		planWg.Add(1)
+		go func(ref v1alpha1.SecretRef, req syncRequest) {
+			defer planWg.Done()
+
+			keyPlan := KeyPlan{
+				Key:       ref.Key,
+				Target:    target.name,
+				RequestID: req.RequestID,
+				Desired:   summarize(req.Data),
+			}
+
+			current, err := store.GetSecret(ctx, ref)
+			switch {
+			case errors.Is(err, v1alpha1.ErrKeyNotFound), errors.Is(err, v1alpha1.ErrKeyVersionDeleted):
+				keyPlan.Action = PlanActionCreate
+			case err != nil:
+				keyPlan.Action = PlanActionUnknown
+				keyPlan.Error = fmt.Sprintf("failed to read target: %v", err)
+			case bytes.Equal(current, req.Data):
+				keyPlan.Action = PlanActionUnchanged
+				keyPlan.Current = summarize(current)
+			default:
+				keyPlan.Action = PlanActionUpdate
+				keyPlan.Current = summarize(current)
+			}
+
+			if len(req.Data) == 0 {
+				keyPlan.Action = PlanActionUnknown
+				keyPlan.Error = "empty value"
+			}
+
+			planMu.Lock()
+			keys = append(keys, keyPlan)
+			planMu.Unlock()
+		}(ref, req)
+	}
+	planWg.Wait()
+
+	// Add target keys which would be pruned
+	for _, req := range pruneRequests {
+		pruneKeys, err := getPruneKeys(ctx, store, req)
+		if err != nil {
+			slog.ErrorContext(ctx, fmt.Errorf("failed to plan prune action: %w", err).Error(), target.logAttrs(slog.Any("id", req.RequestID))...)
+			continue
+		}
+
+		for _, ref := range pruneKeys {
+			keyPlan := KeyPlan{
+				Key:       ref.Key,
+				Target:    target.name,
+				RequestID: req.RequestID,
+				Action:    PlanActionDelete,
+			}
+			if current, err := store.GetSecret(ctx, ref); err == nil {
+				keyPlan.Current = summarize(current)
+			}
+			keys = append(keys, keyPlan)
+		}
+	}
+
+	// Return response
+	sortKeyPlans(keys)
+
+	plan := &PlanResult{
+		Keys:       keys,
+		Collisions: target.collisions,
+		Failed:     target.failedActions,
+		PlannedAt:  time.Now(),
+	}
+	plan.count()
+
+	return plan
+}
+
+// combinePlans returns PlanResult which sums up all target plans.
+func combinePlans(targets []TargetPlan, failedActions []int) *PlanResult {
+	if len(targets) == 1 {
+		plan := targets[0].PlanResult
+		plan.Targets = targets
+		return &plan
+	}
+
+	plan := &PlanResult{
+		Failed:    failedActions,
+		Targets:   targets,
+		PlannedAt: time.Now(),
+	}
+	for _, target := range targets {
+		plan.Keys = append(plan.Keys, target.Keys...)
+		for _, collision := range target.Collisions {
+			if !slices.ContainsFunc(plan.Collisions, collision.equal) {
+				plan.Collisions = append(plan.Collisions, collision)
+			}
+		}
+	}
+	slices.SortStableFunc(plan.Collisions, func(a, b Collision) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	sortKeyPlans(plan.Keys)
+	plan.count()
+
+	return plan
+}
+
+// count sets the number of keys for each planned action.
+func (plan *PlanResult) count() {
+	for _, keyPlan := range plan.Keys {
+		switch keyPlan.Action {
+		case PlanActionCreate:
+			plan.Create++
+		case PlanActionUpdate:
+			plan.Update++
+		case PlanActionUnchanged:
+			plan.Unchanged++
+		case PlanActionDelete:
+			plan.Delete++
+		case PlanActionUnknown:
+			plan.Unknown++
+		}
+	}
+}
+
+// sortKeyPlans sorts key plans by key and target.
+func sortKeyPlans(keys []KeyPlan) {
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Key != keys[j].Key {
+			return keys[i].Key < keys[j].Key
+		}
+		return keys[i].Target < keys[j].Target
+	})
+}
@@ -21,12 +21,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 	"text/template"
 
 	"github.com/iancoleman/strcase"
 	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/singleflight"
 
 	"github.com/bank-vaults/secret-sync/pkg/apis/v1alpha1"
 )
@@ -44,23 +46,42 @@ type fetchResponse struct {
 }
 
 type syncRequest struct {
-	RequestID int
-	ActionRef *v1alpha1.SyncAction
-	Data      []byte
+	RequestID  int
+	ActionRef  *v1alpha1.SyncAction
+	SourceRefs []v1alpha1.SecretRef // source secrets used to create Data, sorted by key
+	Data       []byte
+}
+
+// fetchKey identifies a fetched secret by its key and dereferenced version.
+// This ensures that equal references share cached data regardless of version pointers,
+// and that the same key can be fetched at different versions.
+type fetchKey struct {
+	Key     string
+	Version string
+}
+
+func fetchKeyFor(ref v1alpha1.SecretRef) fetchKey {
+	return fetchKey{
+		Key:     ref.Key,
+		Version: ref.GetVersion(),
+	}
 }
 
 // processor is used to optimally fetch secrets from a source to internal fetched map.
 type processor struct {
-	mu      sync.RWMutex
-	source  v1alpha1.StoreReader
-	fetched map[v1alpha1.SecretRef][]byte
+	ctx      context.Context // bounds shared fetches, which are not tied to a single caller
+	mu       sync.RWMutex
+	source   v1alpha1.StoreReader
+	fetched  map[fetchKey][]byte
+	inflight singleflight.Group
 }
 
-func newProcessor(source v1alpha1.StoreReader) *processor {
+func newProcessor(ctx context.Context, source v1alpha1.StoreReader) *processor {
 	return &processor{
+		ctx:     ctx,
 		mu:      sync.RWMutex{},
 		source:  source,
-		fetched: map[v1alpha1.SecretRef][]byte{},
+		fetched: map[fetchKey][]byte{},
 	}
 }
 
@@ -75,7 +96,11 @@ func (p *processor) GetSyncRequests(ctx context.Context, reqID int, req v1alpha1
 			return nil, err
 		}
 
-		syncRef := *req.FromRef
+		// Source version is not synced to target
+		syncRef := v1alpha1.SecretRef{
+			Key:     req.FromRef.Key,
+			Version: nil,
+		}
 		if req.Target.Key != nil {
 			syncRef.Key = *req.Target.Key
 		}
@@ -90,9 +115,10 @@ func (p *processor) GetSyncRequests(ctx context.Context, reqID int, req v1alpha1
 
 		return map[v1alpha1.SecretRef]syncRequest{
 			syncRef: {
-				Data:      syncValue,
-				ActionRef: &req,
-				RequestID: reqID,
+				Data:       syncValue,
+				ActionRef:  &req,
+				RequestID:  reqID,
+				SourceRefs: []v1alpha1.SecretRef{*req.FromRef},
 			},
 		}, nil
 
@@ -116,7 +142,7 @@ func (p *processor) GetSyncRequests(ctx context.Context, reqID int, req v1alpha1
 
 			templateData := make(map[string]string)
 			for ref, resp := range fetchResps {
-				keyName := strcase.ToLowerCamel(ref.GetName())
+				keyName := strcase.ToLowerCamel(queryRelativeKey(*req.FromQuery, ref))
 				templateData[keyName] = string(resp.Data)
 			}
 
@@ -131,9 +157,10 @@ func (p *processor) GetSyncRequests(ctx context.Context, reqID int, req v1alpha1
 
 			return map[v1alpha1.SecretRef]syncRequest{
 				syncRef: {
-					RequestID: reqID,
-					ActionRef: &req,
-					Data:      syncValue,
+					RequestID:  reqID,
+					ActionRef:  &req,
+					SourceRefs: sortedRefs(fetchResps),
+					Data:       syncValue,
 				},
 			}, nil
 		}
@@ -147,7 +174,7 @@ func (p *processor) GetSyncRequests(ctx context.Context, reqID int, req v1alpha1
 		for ref, resp := range fetchResps {
 			syncRef := ref
 			if req.Target.KeyPrefix != nil {
-				syncRef.Key = *req.Target.KeyPrefix + ref.GetName()
+				syncRef.Key = *req.Target.KeyPrefix + queryRelativeKey(*req.FromQuery, ref)
 			}
 
 			syncValue := resp.Data
@@ -159,9 +186,10 @@ func (p *processor) GetSyncRequests(ctx context.Context, reqID int, req v1alpha1
 			}
 
 			syncMap[syncRef] = syncRequest{
-				Data:      syncValue,
-				ActionRef: &req,
-				RequestID: reqID,
+				Data:       syncValue,
+				ActionRef:  &req,
+				RequestID:  reqID,
+				SourceRefs: []v1alpha1.SecretRef{ref},
 			}
 		}
 		return syncMap, nil
@@ -214,9 +242,10 @@ func (p *processor) GetSyncRequests(ctx context.Context, reqID int, req v1alpha1
 
 		return map[v1alpha1.SecretRef]syncRequest{
 			syncRef: {
-				RequestID: reqID,
-				ActionRef: &req,
-				Data:      syncValue,
+				RequestID:  reqID,
+				ActionRef:  &req,
+				SourceRefs: sortedRefs(fetchResps),
+				Data:       syncValue,
 			},
 		}, nil
 	}
@@ -224,20 +253,59 @@ func (p *processor) GetSyncRequests(ctx context.Context, reqID int, req v1alpha1
 	return nil, errors.New("no sources specified")
 }
 
+// sortedRefs returns fetched source refs sorted by key.
+func sortedRefs(fetched map[v1alpha1.SecretRef]fetchResponse) []v1alpha1.SecretRef {
+	refs := make([]v1alpha1.SecretRef, 0, len(fetched))
+	for ref := range fetched {
+		refs = append(refs, ref)
+	}
+	sort.Slice(refs, func(i, j int) bool {
+		return refs[i].Key < refs[j].Key
+	})
+
+	return refs
+}
+
 // FetchFromRef fetches v1alpha1.SecretRef data from reference or from internal fetch store.
 func (p *processor) FetchFromRef(ctx context.Context, fromRef v1alpha1.SecretRef) (*fetchResponse, error) {
 	// Get from fetch store
 	data, exists := p.getFetchedSecret(fromRef)
 
-	// Fetch and save if not found
+	// Fetch and save if not found.
+	// Concurrent fetches of the same key version are deduplicated,
+	// and the shared fetch is not cancelled if one of its callers is.
+	// Callers stop waiting for the shared fetch once their ctx is done,
+	// so per-action timeouts apply even though the fetch runs on p.ctx.
 	if !exists {
-		var err error
-		data, err = p.source.GetSecret(ctx, fromRef)
-		if err != nil {
+		if err := ctx.Err(); err != nil {
 			return nil, err
 		}
 
-		p.addFetchedSecret(fromRef, data)
+		key := fetchKeyFor(fromRef)
+		result := p.inflight.DoChan(fmt.Sprintf("%q@%q", key.Key, key.Version), func() (interface{}, error) {
+			if data, exists := p.getFetchedSecret(fromRef); exists {
+				return data, nil
+			}
+
+			data, err := p.source.GetSecret(p.ctx, fromRef)
+			if err != nil {
+				return nil, err
+			}
+
+			p.addFetchedSecret(fromRef, data)
+			return data, nil
+		})
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case res := <-result:
+			if res.Err != nil {
+				return nil, res.Err
+			}
+
+			data = res.Val.([]byte)
+		}
 	}
 
 	// Return
@@ -247,6 +315,31 @@ func (p *processor) FetchFromRef(ctx context.Context, fromRef v1alpha1.SecretRef
 	}, nil
 }
 
+// queryRelativeKey returns key of a queried ref relative to the query path.
+// For non-recursive queries, this is the key name, e.g. "key" for "/path/key",
+// otherwise the nested path is preserved, e.g. "nested/key" for "/path/nested/key".
+func queryRelativeKey(query v1alpha1.SecretQuery, ref v1alpha1.SecretRef) string {
+	if !query.IsRecursive() {
+		return ref.GetName()
+	}
+
+	queryPath := ""
+	if query.Path != nil {
+		queryPath = strings.Trim(*query.Path, "/")
+	}
+
+	keyPath := strings.Trim(ref.Key, "/")
+	if queryPath == "" {
+		return keyPath
+	}
+
+	if relativeKey, ok := strings.CutPrefix(keyPath, queryPath+"/"); ok {
+		return relativeKey
+	}
+
+	return ref.GetName()
+}
+
 // FetchFromQuery fetches v1alpha1.SecretRef data from query or from internal fetch store.
 func (p *processor) FetchFromQuery(ctx context.Context, fromQuery v1alpha1.SecretQuery) (map[v1alpha1.SecretRef]fetchResponse, error) {
 	// List secrets from source
@@ -350,7 +443,7 @@ func (p *processor) getFetchedSecret(ref v1alpha1.SecretRef) ([]byte, bool) {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
-	res, ok := p.fetched[ref]
+	res, ok := p.fetched[fetchKeyFor(ref)]
 	return res, ok
 }
 
@@ -359,7 +452,7 @@ func (p *processor) addFetchedSecret(ref v1alpha1.SecretRef, value []byte) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	p.fetched[ref] = value
+	p.fetched[fetchKeyFor(ref)] = value
 }
 
 func getTemplatedValue(syncTemplate *v1alpha1.SyncTemplate, templateData interface{}) ([]byte, error) {
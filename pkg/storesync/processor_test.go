@@ -0,0 +1,92 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storesync
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bank-vaults/secret-sync/pkg/apis/v1alpha1"
+)
+
+func TestFetchFromRefShared(t *testing.T) {
+	source := &blockingReader{started: make(chan struct{}), release: make(chan struct{})}
+	p := newProcessor(context.Background(), source)
+	ref := v1alpha1.SecretRef{Key: "/db/password"}
+
+	// First caller starts the shared fetch and is cancelled while waiting
+	firstCtx, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error)
+	go func() {
+		_, err := p.FetchFromRef(firstCtx, ref)
+		firstErr <- err
+	}()
+	<-source.started
+
+	// Second caller joins the shared fetch
+	second := make(chan []byte)
+	go func() {
+		resp, err := p.FetchFromRef(context.Background(), ref)
+		assert.NoError(t, err)
+		second <- resp.Data
+	}()
+
+	cancel()
+	assert.ErrorIs(t, <-firstErr, context.Canceled)
+
+	// Shared fetch is not cancelled with the first caller
+	close(source.release)
+	assert.Equal(t, []byte("secret"), <-second)
+	require.Equal(t, 1, source.gets)
+}
+
+func TestFetchFromRefTimeout(t *testing.T) {
+	source := &blockingReader{started: make(chan struct{}), release: make(chan struct{})}
+	defer close(source.release)
+	p := newProcessor(context.Background(), source)
+
+	// Caller stops waiting for a blocked shared fetch once its timeout passes
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := p.FetchFromRef(ctx, v1alpha1.SecretRef{Key: "/db/password"})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+// blockingReader is a source whose reads wait until released or cancelled.
+type blockingReader struct {
+	started chan struct{}
+	release chan struct{}
+	gets    int
+}
+
+func (r *blockingReader) GetSecret(ctx context.Context, _ v1alpha1.SecretRef) ([]byte, error) {
+	r.gets++
+	close(r.started)
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-r.release:
+		return []byte("secret"), nil
+	}
+}
+
+func (r *blockingReader) ListSecretKeys(context.Context, v1alpha1.SecretQuery) ([]v1alpha1.SecretRef, error) {
+	return nil, v1alpha1.ErrKeyNotFound
+}
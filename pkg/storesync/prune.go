@@ -0,0 +1,112 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storesync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bank-vaults/secret-sync/pkg/apis/v1alpha1"
+)
+
+// pruneRequest defines a pruning action and target keys which must be kept.
+type pruneRequest struct {
+	RequestID int
+	ActionRef *v1alpha1.SyncAction
+	Synced    map[string]struct{} // normalized target keys synced by any action
+}
+
+// syncedKeys returns normalized keys of all requests to a target.
+// Keys written by other actions under the same prefix are not pruned.
+func syncedKeys(requests map[v1alpha1.SecretRef]syncRequest) map[string]struct{} {
+	synced := make(map[string]struct{}, len(requests))
+	for ref := range requests {
+		synced[normalizedKey(ref.Key)] = struct{}{}
+	}
+
+	return synced
+}
+
+// isPruneAction checks if stale target keys should be deleted for an action.
+func isPruneAction(action v1alpha1.SyncAction) bool {
+	return action.Prune != nil && *action.Prune
+}
+
+// validatePruneAction checks if an action can be used to prune target keys.
+func validatePruneAction(action v1alpha1.SyncAction, target v1alpha1.StoreWriter) error {
+	if action.FromQuery == nil || action.Target.KeyPrefix == nil {
+		return errors.New("requires 'fromQuery' and 'target.keyPrefix' for 'prune'")
+	}
+
+	if _, ok := target.(v1alpha1.StoreReader); !ok {
+		return errors.New("requires target store which supports listing for 'prune'")
+	}
+
+	return nil
+}
+
+// getPruneKeys returns existing keys under action target key prefix which were not synced.
+// Nested keys are only considered if the action source query is recursive.
+func getPruneKeys(ctx context.Context, target v1alpha1.StoreReader, req pruneRequest) ([]v1alpha1.SecretRef, error) {
+	// Key prefix can also select key names, e.g. "/path/prefix-"
+	// Nested keys are only pruned if they are synced, i.e. for recursive queries
+	keyPrefix := *req.ActionRef.Target.KeyPrefix
+	recursive := req.ActionRef.FromQuery.IsRecursive()
+	query := v1alpha1.SecretQuery{
+		Key:       v1alpha1.Query{Regexp: ".*"},
+		Recursive: &recursive,
+		MaxDepth:  req.ActionRef.FromQuery.MaxDepth,
+	}
+	if dir := keyPrefix[:strings.LastIndex(keyPrefix, "/")+1]; dir != "" {
+		query.Path = &dir
+	}
+
+	// List target keys
+	refs, err := target.ListSecretKeys(ctx, query)
+	if err != nil {
+		if errors.Is(err, v1alpha1.ErrKeyNotFound) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("failed while doing query %v: %w", query, err)
+	}
+
+	var result []v1alpha1.SecretRef
+	for _, ref := range refs {
+		key := normalizedKey(ref.Key)
+		if !strings.HasPrefix(key, normalizedKey(keyPrefix)) {
+			continue
+		}
+
+		if _, synced := req.Synced[key]; synced {
+			continue
+		}
+
+		// Stores can still list deleted keys, e.g. soft-deleted Vault KV v2 secrets
+		if _, err := target.GetSecret(ctx, ref); errors.Is(err, v1alpha1.ErrKeyNotFound) || errors.Is(err, v1alpha1.ErrKeyVersionDeleted) {
+			continue
+		}
+		result = append(result, ref)
+	}
+
+	return result, nil
+}
+
+// normalizedKey returns a key without leading slash, as stores may list keys in either form.
+func normalizedKey(key string) string {
+	return strings.TrimPrefix(key, "/")
+}
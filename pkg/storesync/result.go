@@ -0,0 +1,107 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storesync
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+
+	"github.com/bank-vaults/secret-sync/pkg/apis/v1alpha1"
+)
+
+// Outcome defines what happened to a target key during Sync.
+type Outcome string
+
+const (
+	OutcomeSynced     Outcome = "synced"
+	OutcomeUnchanged  Outcome = "unchanged"
+	OutcomePruned     Outcome = "pruned"
+	OutcomeSkipped    Outcome = "skipped" // key not found or sync was aborted
+	OutcomeConflicted Outcome = "conflicted"
+	OutcomeFailed     Outcome = "failed"
+)
+
+// KeyResult defines the outcome of syncing a single target key.
+type KeyResult struct {
+	RequestID  int                  `json:"action"`
+	SourceRefs []v1alpha1.SecretRef `json:"sourceRefs,omitempty"`
+	Target     string               `json:"target,omitempty"` // name of the target, empty if there is only one target
+	Key        string               `json:"key"`
+	Outcome    Outcome              `json:"outcome"`
+	Error      string               `json:"error,omitempty"`
+	DurationMs int64                `json:"durationMs"`          // time spent on the key in milliseconds
+	ValueHash  string               `json:"valueHash,omitempty"` // HMAC-SHA-256 of the synced value, keyed per run
+
+	// SHA-256 of the synced value, which is the same across runs.
+	// It is only used for audit records and is not reported.
+	ValueDigest string `json:"-"`
+}
+
+// Failed checks if the key was not synced as requested.
+func (r KeyResult) Failed() bool {
+	switch r.Outcome {
+	case OutcomeSynced, OutcomeUnchanged, OutcomePruned:
+		return false
+	default:
+		return true
+	}
+}
+
+// newValueHasher returns a function which hashes values with HMAC-SHA-256 keyed
+// with a random secret. Hashes can only be compared within the same sync or plan
+// and cannot be used to guess values. Empty values are not hashed.
+func newValueHasher() (func([]byte) string, error) {
+	hashKey := make([]byte, sha256.Size)
+	if _, err := rand.Read(hashKey); err != nil {
+		return nil, fmt.Errorf("failed to generate hash key: %w", err)
+	}
+
+	return func(value []byte) string {
+		if len(value) == 0 {
+			return ""
+		}
+
+		mac := hmac.New(sha256.New, hashKey)
+		mac.Write(value)
+		return hex.EncodeToString(mac.Sum(nil))
+	}, nil
+}
+
+// digestValue returns the SHA-256 hash of a value, or empty string for an empty value.
+func digestValue(value []byte) string {
+	if len(value) == 0 {
+		return ""
+	}
+
+	sum := sha256.Sum256(value)
+	return hex.EncodeToString(sum[:])
+}
+
+// sortResults sorts results by key, target, and action.
+func sortResults(results []KeyResult) {
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Key != results[j].Key {
+			return results[i].Key < results[j].Key
+		}
+		if results[i].Target != results[j].Target {
+			return results[i].Target < results[j].Target
+		}
+		return results[i].RequestID < results[j].RequestID
+	})
+}
@@ -0,0 +1,96 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storesync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/bank-vaults/secret-sync/pkg/apis/v1alpha1"
+)
+
+// maxBackoffShift prevents overflow when computing exponential backoff.
+const maxBackoffShift = 30
+
+// retrier retries requests which failed due to temporary errors.
+type retrier struct {
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+}
+
+// newRetrier returns a retrier for given policy, or nil if requests should not be retried.
+func newRetrier(policy *v1alpha1.RetryPolicy) (*retrier, error) {
+	if policy == nil {
+		return nil, nil
+	}
+
+	if policy.GetMaxAttempts() < 1 {
+		return nil, errors.New("'maxAttempts' must be positive")
+	}
+
+	initialBackoff, err := time.ParseDuration(policy.GetInitialBackoff())
+	if err != nil || initialBackoff <= 0 {
+		return nil, fmt.Errorf("invalid 'initialBackoff' %q", policy.GetInitialBackoff())
+	}
+
+	maxBackoff, err := time.ParseDuration(policy.GetMaxBackoff())
+	if err != nil || maxBackoff < initialBackoff {
+		return nil, fmt.Errorf("invalid 'maxBackoff' %q, must not be less than 'initialBackoff'", policy.GetMaxBackoff())
+	}
+
+	return &retrier{
+		maxAttempts:    policy.GetMaxAttempts(),
+		initialBackoff: initialBackoff,
+		maxBackoff:     maxBackoff,
+	}, nil
+}
+
+// do calls fn until it succeeds, fails with a non-retryable error, or runs out of attempts.
+// Only errors wrapping v1alpha1.ErrTransient are retried.
+func (r *retrier) do(ctx context.Context, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || !errors.Is(err, v1alpha1.ErrTransient) || attempt >= r.maxAttempts {
+			return err
+		}
+
+		backoff := r.backoff(attempt)
+		slog.DebugContext(ctx, fmt.Sprintf("Retrying failed store request: %v", err), slog.Any("attempt", attempt), slog.Any("backoff", backoff))
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// backoff returns how long to wait after a failed attempt.
+// Delay doubles with each attempt up to max backoff, and is randomized
+// between half and full delay so that concurrent retries do not align.
+func (r *retrier) backoff(attempt int) time.Duration {
+	backoff := r.maxBackoff
+	if shift := attempt - 1; shift < maxBackoffShift {
+		backoff = min(r.initialBackoff<<shift, r.maxBackoff)
+	}
+
+	half := backoff / 2
+	return half + rand.N(backoff-half+1)
+}
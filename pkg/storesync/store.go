@@ -0,0 +1,175 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storesync
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bank-vaults/secret-sync/pkg/apis/v1alpha1"
+)
+
+// storePolicy defines limits and retries applied to requests made to a store.
+type storePolicy struct {
+	limiter *limiter // nil if requests are not limited
+	retrier *retrier // nil if requests are not retried
+}
+
+func newStorePolicy(limits *v1alpha1.StoreLimits, retryPolicy *v1alpha1.RetryPolicy) (*storePolicy, error) {
+	if err := validateLimits(limits); err != nil {
+		return nil, fmt.Errorf("invalid limits: %w", err)
+	}
+
+	retrier, err := newRetrier(retryPolicy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid retry policy: %w", err)
+	}
+
+	return &storePolicy{
+		limiter: newLimiter(limits),
+		retrier: retrier,
+	}, nil
+}
+
+// syncContextKey is the context key of the sync context of a write.
+type syncContextKey struct{}
+
+// withSyncContext returns a copy of write context ctx which holds the context of its sync.
+func withSyncContext(ctx, syncCtx context.Context) context.Context {
+	return context.WithValue(ctx, syncContextKey{}, syncCtx)
+}
+
+// waitContext returns a context for waits between requests made with ctx.
+// For writes, it is also cancelled with their sync, so that writes which are
+// waiting for limits or retries stop without waiting for the grace period.
+func waitContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	syncCtx, ok := ctx.Value(syncContextKey{}).(context.Context)
+	if !ok {
+		return ctx, func() {}
+	}
+
+	waitCtx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(syncCtx, cancel)
+	return waitCtx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// do makes a request within limits, retrying it if needed.
+func (p *storePolicy) do(ctx context.Context, request func() error) error {
+	waitCtx, cancel := waitContext(ctx)
+	defer cancel()
+
+	attempt := func() error {
+		if p.limiter != nil {
+			release, err := p.limiter.acquire(waitCtx)
+			if err != nil {
+				return err
+			}
+			defer release()
+		}
+
+		return request()
+	}
+
+	if p.retrier == nil {
+		return attempt()
+	}
+
+	return p.retrier.do(waitCtx, attempt)
+}
+
+// wrapStores applies configured limits and retries to source and target requests.
+// If the target also supports reading, so does the returned target.
+func wrapStores(source v1alpha1.StoreReader, target v1alpha1.StoreWriter, opts *options) (v1alpha1.StoreReader, v1alpha1.StoreWriter, error) {
+	sourcePolicy, err := newStorePolicy(opts.sourceLimits, opts.retryPolicy)
+	if err != nil {
+		return nil, nil, fmt.Errorf("source: %w", err)
+	}
+
+	targetPolicy, err := newStorePolicy(opts.targetLimits, opts.retryPolicy)
+	if err != nil {
+		return nil, nil, fmt.Errorf("target: %w", err)
+	}
+
+	if source != nil && (sourcePolicy.limiter != nil || sourcePolicy.retrier != nil) {
+		source = &policyReader{reader: source, policy: sourcePolicy}
+	}
+
+	if target != nil && (targetPolicy.limiter != nil || targetPolicy.retrier != nil) {
+		writer := &policyWriter{writer: target, policy: targetPolicy}
+		if reader, ok := target.(v1alpha1.StoreReader); ok {
+			target = &policyClient{
+				policyReader: &policyReader{reader: reader, policy: targetPolicy},
+				policyWriter: writer,
+			}
+		} else {
+			target = writer
+		}
+	}
+
+	return source, target, nil
+}
+
+type policyReader struct {
+	reader v1alpha1.StoreReader
+	policy *storePolicy
+}
+
+func (r *policyReader) GetSecret(ctx context.Context, key v1alpha1.SecretRef) ([]byte, error) {
+	var value []byte
+	err := r.policy.do(ctx, func() error {
+		var err error
+		value, err = r.reader.GetSecret(ctx, key)
+		return err
+	})
+
+	return value, err
+}
+
+func (r *policyReader) ListSecretKeys(ctx context.Context, query v1alpha1.SecretQuery) ([]v1alpha1.SecretRef, error) {
+	var refs []v1alpha1.SecretRef
+	err := r.policy.do(ctx, func() error {
+		var err error
+		refs, err = r.reader.ListSecretKeys(ctx, query)
+		return err
+	})
+
+	return refs, err
+}
+
+type policyWriter struct {
+	writer v1alpha1.StoreWriter
+	policy *storePolicy
+}
+
+func (w *policyWriter) SetSecret(ctx context.Context, key v1alpha1.SecretRef, value []byte) error {
+	return w.policy.do(ctx, func() error {
+		return w.writer.SetSecret(ctx, key, value)
+	})
+}
+
+func (w *policyWriter) DeleteSecret(ctx context.Context, key v1alpha1.SecretRef) error {
+	return w.policy.do(ctx, func() error {
+		return w.writer.DeleteSecret(ctx, key)
+	})
+}
+
+// policyClient shares a policy between reads and writes of a store.
+type policyClient struct {
+	*policyReader
+	*policyWriter
+}
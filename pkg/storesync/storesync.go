@@ -12,15 +12,16 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-// TODO: Expose a way to handle key collisions (for both fetch and sync)
-
 package storesync
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -32,116 +33,636 @@ import (
 
 var syncMu sync.Mutex
 
+var (
+	// ErrSyncFailed is returned by Sync when some keys were not synced and
+	// failure policy is v1alpha1.FailurePolicyFailAtEnd.
+	ErrSyncFailed = errors.New("sync failed")
+
+	// ErrSyncAborted is returned by Sync when it was stopped on the first failure and
+	// failure policy is v1alpha1.FailurePolicyFailFast.
+	ErrSyncAborted = errors.New("sync aborted")
+)
+
 // Status defines response data returned by Sync.
 type Status struct {
-	Total    uint32    //  total number of keys marked for sync
-	Synced   uint32    //  number of successful syncs
-	Success  bool      //  if Sync was successful
-	Status   string    //  an arbitrary status message
-	SyncedAt time.Time //  completion timestamp
+	Total       uint32         `json:"total"`                 //  total number of keys marked for sync
+	Synced      uint32         `json:"synced"`                //  number of successful syncs
+	Unchanged   uint32         `json:"unchanged"`             //  number of keys skipped as target already had the same value
+	Pruned      uint32         `json:"pruned"`                //  number of keys deleted from target by pruning
+	Failed      []int          `json:"failed,omitempty"`      //  ids of actions which could not be fetched from source
+	Unprocessed []string       `json:"unprocessed,omitempty"` //  keys not synced because Sync was cancelled
+	Conflicted  []string       `json:"conflicted,omitempty"`  //  keys not synced due to concurrent updates on target
+	Collisions  []Collision    `json:"collisions,omitempty"`  //  keys scheduled for sync by multiple actions
+	Results     []KeyResult    `json:"results"`               //  outcome for each synced and pruned key, sorted by key
+	Success     bool           `json:"success"`               //  if Sync was successful
+	Status      string         `json:"status"`                //  an arbitrary status message
+	SyncedAt    time.Time      `json:"syncedAt"`              //  completion timestamp
+	Targets     []TargetStatus `json:"targets,omitempty"`     //  status of each target, only set by SyncTargets
 }
 
 // Sync will synchronize keys from source to target based on provided specs.
+// Depending on the failure policy, Sync can return both Status and an error
+// wrapping ErrSyncFailed or ErrSyncAborted. Status is nil if nothing was synced.
+//
+// If ctx is cancelled, Sync stops scheduling new writes, lets in-flight writes
+// finish within the write grace period, and returns partial Status with an error
+// wrapping ErrSyncAborted.
 func Sync(ctx context.Context,
 	source v1alpha1.StoreReader,
 	target v1alpha1.StoreWriter,
 	actions []v1alpha1.SyncAction,
+	opts ...Option,
 ) (*Status, error) {
-	// Validate
-	if source == nil {
-		return nil, errors.New("source is nil")
+	status, err := SyncTargets(ctx, source, []Target{{Store: target}}, actions, opts...)
+	if status == nil {
+		return nil, err
 	}
 
-	if target == nil {
-		return nil, errors.New("target is nil")
-	}
+	return &status.Targets[0].Status, err
+}
 
-	if len(actions) == 0 {
-		return nil, errors.New("no actions provided")
+// SyncTargets will synchronize keys from source to each target based on provided specs.
+// Keys are fetched and templated only once, and written to all targets concurrently.
+// Returned Status sums up all targets and lists Status of each target in Status.Targets.
+// Errors are returned the same way as by Sync.
+func SyncTargets(ctx context.Context,
+	source v1alpha1.StoreReader,
+	targets []Target,
+	actions []v1alpha1.SyncAction,
+	opts ...Option,
+) (*Status, error) {
+	syncOpts := newOptions(opts)
+	fetched, err := fetchTargets(ctx, source, targets, actions, syncOpts)
+	if err != nil {
+		return nil, err
 	}
 
-	// Define data stores
-	syncRequests := make(map[v1alpha1.SecretRef]syncRequest)
-	processor := newProcessor(source)
+	// Sync to each target in a separate goroutine.
+	// With failFast policy, the first failure stops writes to all targets.
+	syncCtx, abort := context.WithCancelCause(ctx)
+	defer abort(nil)
+	var targetWg sync.WaitGroup
+	statuses := make([]TargetStatus, len(fetched.targets))
+	for i, target := range fetched.targets {
+		targetWg.Add(1)
+		go func() {
+			defer targetWg.Done()
 
-	// Get sync plan for each request in a separate goroutine.
-	// If the same secret needs to be synced more than once, abort sync.
-	fetchGroup, fetchCtx := errgroup.WithContext(ctx)
+			statuses[i] = TargetStatus{
+				Name:   target.name,
+				Status: *syncTarget(syncCtx, target, fetched, syncOpts, abort),
+			}
+		}()
+	}
+	targetWg.Wait()
 
-	for id, action := range actions {
-		func(id int, action v1alpha1.SyncAction) {
-			fetchGroup.Go(func() error {
-				// Fetch keys to store
-				requests, err := processor.GetSyncRequests(fetchCtx, id, action)
-				if err != nil {
-					slog.WarnContext(ctx, fmt.Sprintf("Failed to fetch sync action: %v", err), slog.Any("id", id))
-					return nil
-				}
+	resp := combineStatuses(statuses, fetched.failedActions)
 
-				// Add to sync data
-				syncMu.Lock()
-				defer syncMu.Unlock()
-				for ref, request := range requests {
-					if _, exists := syncRequests[ref]; exists {
-						// This is a critical error; stop everything
-						return fmt.Errorf("key %v was schedule for sync more than once", ref)
-					}
-
-					syncRequests[ref] = request
-				}
-
-				return nil
-			})
-		}(id, action)
+	switch {
+	case resp.Success:
+		return resp, nil
+	case ctx.Err() != nil:
+		return resp, fmt.Errorf("%w: %w", ErrSyncAborted, ctx.Err())
+	case syncOpts.failurePolicy == v1alpha1.FailurePolicyFailFast:
+		return resp, fmt.Errorf("%w: %s", ErrSyncAborted, resp.Status)
+	case syncOpts.failurePolicy == v1alpha1.FailurePolicyFailAtEnd:
+		return resp, fmt.Errorf("%w: %s", ErrSyncFailed, resp.Status)
+	default:
+		return resp, nil
 	}
+}
 
-	// Wait fetch
-	if err := fetchGroup.Wait(); err != nil {
-		return nil, fmt.Errorf("aborted syncing, reason: %w", err)
+// syncTarget writes requested keys to a single target and prunes stale keys from it.
+// With failFast policy, abort cancels ctx with ErrSyncAborted on the first failure.
+func syncTarget(ctx context.Context,
+	target *targetRequests,
+	fetched *fetchResult,
+	opts *options,
+	abort context.CancelCauseFunc,
+) *Status {
+	store, syncRequests, pruneRequests := target.store, target.syncRequests, target.pruneRequests
+	timeouts := fetched.timeouts
+	failFast := opts.failurePolicy == v1alpha1.FailurePolicyFailFast
+	fail := func() {
+		if failFast {
+			abort(ErrSyncAborted)
+		}
 	}
 
 	// Sync requests from source to target store.
-	// Do sync for each plan item in a separate goroutine.
-	var syncWg sync.WaitGroup
-	var syncCounter atomic.Uint32
+	// Do sync for each plan item in a separate goroutine,
+	// but do not schedule more writes than the target allows.
+	syncGroup, syncCtx := errgroup.WithContext(ctx)
+	if opts.targetLimits != nil && opts.targetLimits.MaxConcurrency > 0 {
+		syncGroup.SetLimit(opts.targetLimits.MaxConcurrency)
+	}
+	var syncCounter, unchangedCounter atomic.Uint32
+	var resultsMu sync.Mutex
+	var conflicted, unprocessed []string
+	var results []KeyResult
+
+	// skip reports a key which is not written because the sync was aborted or cancelled.
+	// Keys of cancelled syncs are reported as unprocessed.
+	skip := func(result *KeyResult) bool {
+		if ctx.Err() == nil {
+			return false
+		}
+
+		cause := context.Cause(ctx)
+		result.Outcome = OutcomeSkipped
+		result.Error = cause.Error()
+		if !errors.Is(cause, ErrSyncAborted) {
+			resultsMu.Lock()
+			unprocessed = append(unprocessed, result.Key)
+			resultsMu.Unlock()
+		}
+
+		return true
+	}
+
 	for ref, req := range syncRequests {
-		syncWg.Add(1)
-		go func(ref v1alpha1.SecretRef, req syncRequest) {
-			defer syncWg.Done()
+		result := KeyResult{
+			RequestID:   req.RequestID,
+			SourceRefs:  req.SourceRefs,
+			Target:      target.name,
+			Key:         ref.Key,
+			ValueHash:   fetched.hashValue(req.Data),
+			ValueDigest: digestValue(req.Data),
+		}
+
+		// Stop scheduling writes after the first failure or once cancelled
+		if skip(&result) {
+			resultsMu.Lock()
+			results = append(results, result)
+			resultsMu.Unlock()
+			continue
+		}
+
+		syncGroup.Go(func() error {
+			start := time.Now()
+			defer func() {
+				result.DurationMs = time.Since(start).Milliseconds()
+
+				resultsMu.Lock()
+				results = append(results, result)
+				resultsMu.Unlock()
+			}()
+
+			// Requests waiting for a free slot can be aborted or cancelled in the meantime
+			if skip(&result) {
+				return nil
+			}
+
+			// Writes which already started are not cancelled with the sync until the grace period ends
+			writeCtx, cancel := writeContext(syncCtx, opts.gracePeriod, timeouts[req.RequestID])
+			defer cancel()
+
+			logAttrs := target.logAttrs(slog.Any("id", req.RequestID), slog.Any("key", ref.Key))
 
 			// Sync
 			var err error
 			if len(req.Data) == 0 {
 				err = errors.New("empty value")
+			} else if isUnchanged(writeCtx, store, ref, req.Data) {
+				slog.DebugContext(ctx, "Skipped unchanged sync action", logAttrs...)
+				result.Outcome = OutcomeUnchanged
+				unchangedCounter.Add(1)
+				return nil
 			} else {
-				err = target.SetSecret(ctx, ref, req.Data)
+				err = store.SetSecret(writeCtx, ref, req.Data)
 			}
 
 			// Handle response
+			if err != nil && isCancelled(ctx, err) {
+				skip(&result)
+				return nil
+			}
 			if err != nil {
+				result.Error = err.Error()
 				if errors.Is(err, v1alpha1.ErrKeyNotFound) { // not found, soft warn
-					slog.WarnContext(ctx, fmt.Sprintf("Skipped sync action: %v", err), slog.Any("id", req.RequestID), slog.Any("key", ref.Key))
+					slog.WarnContext(ctx, fmt.Sprintf("Skipped sync action: %v", err), logAttrs...)
+					result.Outcome = OutcomeSkipped
+				} else if errors.Is(err, v1alpha1.ErrConflict) { // concurrently modified, report
+					slog.ErrorContext(ctx, fmt.Errorf("conflicted sync action: %w", err).Error(), logAttrs...)
+					result.Outcome = OutcomeConflicted
+
+					resultsMu.Lock()
+					conflicted = append(conflicted, ref.Key)
+					resultsMu.Unlock()
 				} else { // otherwise, log error
-					slog.ErrorContext(ctx, fmt.Errorf("failed to sync action: %w", err).Error(), slog.Any("id", req.RequestID), slog.Any("key", ref.Key))
+					slog.ErrorContext(ctx, fmt.Errorf("failed to sync action: %w", err).Error(), logAttrs...)
+					result.Outcome = OutcomeFailed
 				}
 
-				return
+				fail()
+				return nil
 			}
-			slog.InfoContext(ctx, "Successfully synced action", slog.Any("id", req.RequestID), slog.Any("key", ref.Key))
+			slog.InfoContext(ctx, "Successfully synced action", logAttrs...)
+			result.Outcome = OutcomeSynced
 			syncCounter.Add(1)
-		}(ref, req)
+			return nil
+		})
+	}
+	_ = syncGroup.Wait()
+
+	// Delete target keys which no longer exist in source
+	var pruneCount, pruneFailures uint32
+	for _, req := range pruneRequests {
+		if ctx.Err() != nil {
+			break
+		}
+
+		pruneKeys, err := getPruneKeys(ctx, store.(v1alpha1.StoreReader), req)
+		if err != nil {
+			slog.ErrorContext(ctx, fmt.Errorf("failed to prune action: %w", err).Error(), target.logAttrs(slog.Any("id", req.RequestID))...)
+			pruneFailures++
+			fail()
+			continue
+		}
+
+		for _, ref := range pruneKeys {
+			if ctx.Err() != nil {
+				break
+			}
+
+			start := time.Now()
+			result := KeyResult{
+				RequestID: req.RequestID,
+				Target:    target.name,
+				Key:       ref.Key,
+				Outcome:   OutcomePruned,
+			}
+			logAttrs := target.logAttrs(slog.Any("id", req.RequestID), slog.Any("key", ref.Key))
+
+			deleteCtx, cancel := writeContext(ctx, opts.gracePeriod, timeouts[req.RequestID])
+			err := store.DeleteSecret(deleteCtx, ref)
+			cancel()
+			result.DurationMs = time.Since(start).Milliseconds()
+			if errors.Is(err, v1alpha1.ErrKeyNotFound) {
+				// Deleted since listing, nothing was pruned
+				slog.DebugContext(ctx, "Skipped pruning deleted key", logAttrs...)
+				continue
+			}
+			if err != nil {
+				slog.ErrorContext(ctx, fmt.Errorf("failed to prune key: %w", err).Error(), logAttrs...)
+				result.Outcome = OutcomeFailed
+				result.Error = err.Error()
+				results = append(results, result)
+				pruneFailures++
+				fail()
+				continue
+			}
+
+			slog.InfoContext(ctx, "Successfully pruned key", logAttrs...)
+			results = append(results, result)
+			pruneCount++
+		}
 	}
-	syncWg.Wait()
 
 	// Return response
 	syncCount := syncCounter.Load()
+	unchangedCount := unchangedCounter.Load()
 	totalCount := uint32(len(syncRequests))
 
+	sort.Strings(conflicted)
+	sort.Strings(unprocessed)
+	sortResults(results)
+
+	status := fmt.Sprintf("Synced %d out of total %d keys", syncCount, totalCount)
+	if unchangedCount > 0 {
+		status += fmt.Sprintf(", %d keys unchanged", unchangedCount)
+	}
+	if len(pruneRequests) > 0 {
+		status += fmt.Sprintf(", pruned %d keys", pruneCount)
+	}
+	if len(target.failedActions) > 0 {
+		status += fmt.Sprintf(", failed to fetch %d actions", len(target.failedActions))
+	}
+	switch {
+	case errors.Is(context.Cause(ctx), ErrSyncAborted):
+		status += ", aborted on first failure"
+	case ctx.Err() != nil:
+		status += fmt.Sprintf(", cancelled with %d keys unprocessed", len(unprocessed))
+	}
+
 	return &Status{
-		Total:    totalCount,
-		Synced:   syncCount,
-		Success:  totalCount == syncCount,
-		Status:   fmt.Sprintf("Synced %d out of total %d keys", syncCount, totalCount),
-		SyncedAt: time.Now(),
-	}, nil
+		Total:       totalCount,
+		Synced:      syncCount,
+		Unchanged:   unchangedCount,
+		Pruned:      pruneCount,
+		Failed:      target.failedActions,
+		Unprocessed: unprocessed,
+		Conflicted:  conflicted,
+		Collisions:  target.collisions,
+		Results:     results,
+		Success:     totalCount == syncCount+unchangedCount && pruneFailures == 0 && len(target.failedActions) == 0,
+		Status:      status,
+		SyncedAt:    time.Now(),
+	}
+}
+
+// fetchResult defines data fetched from source for all targets.
+type fetchResult struct {
+	targets       []*targetRequests
+	failedActions []int           // sorted ids of actions which could not be fetched
+	timeouts      []time.Duration // timeout of each action, zero if not limited
+	hashValue     func([]byte) string
+}
+
+// targetRequests defines requests to write to a single target and to prune stale keys from it.
+type targetRequests struct {
+	name          string
+	store         v1alpha1.StoreWriter // store wrapped with limits and retries
+	syncRequests  map[v1alpha1.SecretRef]syncRequest
+	pruneRequests []pruneRequest
+	collisions    []Collision
+	failedActions []int // sorted ids of target actions which could not be fetched
+}
+
+// logAttrs returns log attributes identifying the target, if it is named.
+func (t *targetRequests) logAttrs(attrs ...any) []any {
+	if t.name == "" {
+		return attrs
+	}
+
+	return append([]any{slog.String("target", t.name)}, attrs...)
+}
+
+// fetchTargets validates actions and targets, and fetches data from source for each action
+// used by any of the targets. Returns requests to write to each target and to prune stale target keys.
+func fetchTargets(ctx context.Context,
+	source v1alpha1.StoreReader,
+	targets []Target,
+	actions []v1alpha1.SyncAction,
+	opts *options,
+) (*fetchResult, error) {
+	// Validate
+	if source == nil {
+		return nil, errors.New("source is nil")
+	}
+
+	if len(targets) == 0 {
+		return nil, errors.New("no targets provided")
+	}
+
+	if len(actions) == 0 {
+		return nil, errors.New("no actions provided")
+	}
+
+	if err := validateConflictPolicy(opts.conflictPolicy); err != nil {
+		return nil, err
+	}
+
+	switch opts.failurePolicy {
+	case v1alpha1.FailurePolicyContinue, v1alpha1.FailurePolicyFailFast, v1alpha1.FailurePolicyFailAtEnd:
+	default:
+		return nil, fmt.Errorf("unsupported failure policy %q", opts.failurePolicy)
+	}
+
+	timeouts := make([]time.Duration, len(actions))
+	for id, action := range actions {
+		if err := validateConflictPolicy(action.ConflictPolicy); err != nil {
+			return nil, fmt.Errorf("invalid sync action %d: %w", id, err)
+		}
+
+		if action.Timeout != "" {
+			timeout, err := time.ParseDuration(action.Timeout)
+			if err != nil || timeout <= 0 {
+				return nil, fmt.Errorf("invalid sync action %d: invalid 'timeout' %q", id, action.Timeout)
+			}
+			timeouts[id] = timeout
+		}
+	}
+
+	targetActions, err := validateTargets(targets, actions)
+	if err != nil {
+		return nil, err
+	}
+
+	// Apply limits and retries to store requests
+	source, _, err = wrapStores(source, nil, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	stores := make([]v1alpha1.StoreWriter, len(targets))
+	for i, target := range targets {
+		if _, stores[i], err = wrapStores(nil, target.Store, opts); err != nil {
+			return nil, err
+		}
+	}
+
+	// Define data stores
+	actionRequests := make([]map[v1alpha1.SecretRef]syncRequest, len(actions))
+	var failedActions []int
+
+	// Get sync requests for each action used by any target in a separate goroutine.
+	fetchGroup, fetchCtx := errgroup.WithContext(ctx)
+	processor := newProcessor(fetchCtx, source)
+
+	for id, action := range actions {
+		if !slices.ContainsFunc(targetActions, func(ids []int) bool { return slices.Contains(ids, id) }) {
+			continue
+		}
+
+		fetchGroup.Go(func() error {
+			// Fetch keys to store
+			actionCtx, cancel := fetchCtx, context.CancelFunc(func() {})
+			if timeouts[id] > 0 {
+				actionCtx, cancel = context.WithTimeout(fetchCtx, timeouts[id])
+			}
+			defer cancel()
+
+			requests, err := processor.GetSyncRequests(actionCtx, id, action)
+			if err != nil {
+				// Cancelled fetches are reported as failed so that partial status can be returned
+				if opts.failurePolicy == v1alpha1.FailurePolicyFailFast && ctx.Err() == nil {
+					// Stop fetching other actions
+					return fmt.Errorf("%w, failed to fetch sync action %d: %w", ErrSyncAborted, id, err)
+				}
+
+				slog.ErrorContext(ctx, fmt.Errorf("failed to fetch sync action: %w", err).Error(), slog.Any("id", id))
+
+				syncMu.Lock()
+				failedActions = append(failedActions, id)
+				syncMu.Unlock()
+				return nil
+			}
+
+			// Add to sync data
+			syncMu.Lock()
+			actionRequests[id] = requests
+			syncMu.Unlock()
+
+			return nil
+		})
+	}
+
+	// Wait fetch
+	if err := fetchGroup.Wait(); err != nil {
+		return nil, fmt.Errorf("aborted fetching, reason: %w", err)
+	}
+	sort.Ints(failedActions)
+
+	hashValue, err := newValueHasher()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &fetchResult{
+		failedActions: failedActions,
+		timeouts:      timeouts,
+		hashValue:     hashValue,
+	}
+	for i, target := range targets {
+		requests, err := mergeRequests(ctx, actions, targetActions[i], actionRequests, failedActions, opts)
+		if err != nil {
+			if target.Name != "" {
+				return nil, fmt.Errorf("aborted fetching for target %q, %w", target.Name, err)
+			}
+			return nil, fmt.Errorf("aborted fetching, %w", err)
+		}
+
+		requests.name = target.Name
+		requests.store = stores[i]
+		result.targets = append(result.targets, requests)
+	}
+
+	return result, nil
+}
+
+// mergeRequests combines fetched requests of given actions for a single target.
+func mergeRequests(ctx context.Context,
+	actions []v1alpha1.SyncAction,
+	ids []int,
+	actionRequests []map[v1alpha1.SecretRef]syncRequest,
+	failedActions []int,
+	opts *options,
+) (*targetRequests, error) {
+	result := &targetRequests{
+		syncRequests: make(map[v1alpha1.SecretRef]syncRequest),
+	}
+
+	// Combine requests in action order so that collisions are resolved deterministically.
+	// If the same key is scheduled by more than one action, apply the conflict policy
+	// of the action listed later.
+	collisions := make(map[string]*Collision)
+	for _, id := range ids {
+		if slices.Contains(failedActions, id) {
+			result.failedActions = append(result.failedActions, id)
+			continue
+		}
+
+		requests := actionRequests[id]
+		for ref, request := range requests {
+			existing, exists := result.syncRequests[ref]
+			if !exists {
+				result.syncRequests[ref] = request
+				continue
+			}
+
+			policy := actions[id].ConflictPolicy
+			if policy == "" {
+				policy = opts.conflictPolicy
+			}
+
+			resolved, err := resolveConflict(policy, existing, request)
+			if err != nil {
+				// This is a critical error; stop everything
+				return nil, fmt.Errorf("key %v collided for sync actions %d and %d: %w",
+					ref.Key, existing.RequestID, id, err)
+			}
+			result.syncRequests[ref] = resolved
+
+			// Report collision
+			collision, reported := collisions[ref.Key]
+			if !reported {
+				collision = &Collision{Key: ref.Key, Actions: []int{existing.RequestID}}
+				collisions[ref.Key] = collision
+			}
+			collision.Actions = append(collision.Actions, id)
+			collision.Policy = policy
+			collision.Winner = resolved.RequestID
+			if policy == v1alpha1.ConflictPolicyMerge {
+				collision.Winner = -1
+			}
+
+			slog.WarnContext(ctx, "Resolved key collision between sync actions",
+				slog.Any("key", ref.Key), slog.Any("ids", collision.Actions), slog.Any("policy", policy))
+		}
+
+		// Only prune if source keys were fetched, an empty source
+		// likely points to misconfiguration rather than removed keys
+		if isPruneAction(actions[id]) {
+			if len(requests) == 0 {
+				slog.WarnContext(ctx, "Skipped pruning for sync action without source keys", slog.Any("id", id))
+			} else {
+				result.pruneRequests = append(result.pruneRequests, pruneRequest{RequestID: id, ActionRef: &actions[id]})
+			}
+		}
+	}
+	result.collisions = sortedCollisions(collisions)
+
+	// Keep keys synced by any action of the target
+	synced := syncedKeys(result.syncRequests)
+	for i := range result.pruneRequests {
+		result.pruneRequests[i].Synced = synced
+	}
+
+	return result, nil
+}
+
+// writeContext returns a context for a write which started before ctx was cancelled,
+// so that it is only interrupted if it is still running once the grace period after cancellation ends.
+// The write is also limited by timeout, if set.
+func writeContext(ctx context.Context, grace, timeout time.Duration) (context.Context, context.CancelFunc) {
+	writeCtx, cancel := context.WithCancel(withSyncContext(context.WithoutCancel(ctx), ctx))
+	stop := context.AfterFunc(ctx, func() {
+		timer := time.NewTimer(grace)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+			cancel()
+		case <-writeCtx.Done():
+		}
+	})
+	cancelWrite := func() {
+		stop()
+		cancel()
+	}
+
+	if timeout > 0 {
+		timeoutCtx, cancelTimeout := context.WithTimeout(writeCtx, timeout)
+		return timeoutCtx, func() {
+			cancelTimeout()
+			cancelWrite()
+		}
+	}
+
+	return writeCtx, cancelWrite
+}
+
+// isCancelled checks if a request failed because ctx was cancelled before it could complete.
+func isCancelled(ctx context.Context, err error) bool {
+	return ctx.Err() != nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded))
+}
+
+// isUnchanged checks if target already stores the given value.
+// Targets which do not support reading are always written to.
+func isUnchanged(ctx context.Context, target v1alpha1.StoreWriter, ref v1alpha1.SecretRef, value []byte) bool {
+	reader, ok := target.(v1alpha1.StoreReader)
+	if !ok {
+		return false
+	}
+
+	current, err := reader.GetSecret(ctx, ref)
+	if err != nil {
+		if !errors.Is(err, v1alpha1.ErrKeyNotFound) && !errors.Is(err, v1alpha1.ErrKeyVersionDeleted) {
+			slog.DebugContext(ctx, fmt.Sprintf("Failed to read target key for comparison: %v", err), slog.Any("key", ref.Key))
+		}
+		return false
+	}
+
+	return bytes.Equal(current, value)
 }
@@ -14,6 +14,953 @@
 
 package storesync_test
 
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bank-vaults/secret-sync/pkg/apis/v1alpha1"
+	"github.com/bank-vaults/secret-sync/pkg/storesync"
+)
+
+func TestSyncVersions(t *testing.T) {
+	source := newFakeStore()
+	source.versions["/db/password"] = [][]byte{[]byte("previous"), []byte("current")}
+	target := newFakeStore()
+
+	resp, err := storesync.Sync(context.Background(), source, target, []v1alpha1.SyncAction{
+		{
+			FromRef: &v1alpha1.SecretRef{Key: "/db/password"},
+		},
+		{
+			FromRef: &v1alpha1.SecretRef{Key: "/db/password", Version: ptr("1")},
+			Target:  v1alpha1.SyncTarget{Key: ptr("/db/password-previous")},
+		},
+		{
+			FromRef: &v1alpha1.SecretRef{Key: "/db/password", Version: ptr("1")},
+			Target:  v1alpha1.SyncTarget{Key: ptr("/db/password-backup")},
+		},
+	})
+	require.NoError(t, err)
+	assert.True(t, resp.Success)
+
+	assert.Equal(t, []byte("current"), target.latest("/db/password"))
+	assert.Equal(t, []byte("previous"), target.latest("/db/password-previous"))
+	assert.Equal(t, []byte("previous"), target.latest("/db/password-backup"))
+	assert.Equal(t, 2, source.gets, "each key version should be fetched once")
+}
+
+func TestSyncRecursiveQuery(t *testing.T) {
+	source := newFakeStore()
+	source.versions["/app/db"] = [][]byte{[]byte("db")}
+	source.versions["/app/nested/token"] = [][]byte{[]byte("token")}
+	target := newFakeStore()
+
+	resp, err := storesync.Sync(context.Background(), source, target, []v1alpha1.SyncAction{
+		{
+			FromQuery: &v1alpha1.SecretQuery{Path: ptr("/app"), Key: v1alpha1.Query{Regexp: ".*"}, Recursive: ptr(true)},
+			Target:    v1alpha1.SyncTarget{KeyPrefix: ptr("/copy/")},
+		},
+	})
+	require.NoError(t, err)
+	assert.True(t, resp.Success)
+
+	// Nested paths are preserved under key prefix
+	assert.Equal(t, []byte("db"), target.latest("/copy/db"))
+	assert.Equal(t, []byte("token"), target.latest("/copy/nested/token"))
+}
+
+func TestSyncPrune(t *testing.T) {
+	source := newFakeStore()
+	source.versions["/app/db"] = [][]byte{[]byte("db")}
+	source.versions["/app/api"] = [][]byte{[]byte("api")}
+	source.versions["/other/key"] = [][]byte{[]byte("other")}
+	target := newFakeStore()
+	target.versions["/copy/db"] = [][]byte{[]byte("previous")}
+	target.versions["/copy/removed"] = [][]byte{[]byte("removed")}
+	target.versions["/copy/deleted"] = [][]byte{[]byte("deleted"), nil}
+	target.versions["/copy/nested/key"] = [][]byte{[]byte("nested")}
+	target.versions["/copy/other"] = [][]byte{[]byte("previous")}
+	target.versions["/copy-other"] = [][]byte{[]byte("other")}
+	target.versions["/failed/removed"] = [][]byte{[]byte("removed")}
+
+	resp, err := storesync.Sync(context.Background(), source, target, []v1alpha1.SyncAction{
+		{
+			FromQuery: &v1alpha1.SecretQuery{Path: ptr("/app"), Key: v1alpha1.Query{Regexp: ".*"}},
+			Target:    v1alpha1.SyncTarget{KeyPrefix: ptr("/copy/")},
+			Prune:     ptr(true),
+		},
+		{
+			// Keys synced by other actions under the same prefix are kept
+			FromRef: &v1alpha1.SecretRef{Key: "/other/key"},
+			Target:  v1alpha1.SyncTarget{Key: ptr("/copy/other")},
+		},
+		{
+			// Source query fails, so nothing is pruned
+			FromQuery: &v1alpha1.SecretQuery{Path: ptr("/missing"), Key: v1alpha1.Query{Regexp: ".*"}},
+			Target:    v1alpha1.SyncTarget{KeyPrefix: ptr("/failed/")},
+			Prune:     ptr(true),
+		},
+	})
+	require.NoError(t, err)
+	assert.False(t, resp.Success, "failed source query is reported")
+	assert.Equal(t, []int{2}, resp.Failed)
+	assert.Equal(t, uint32(1), resp.Pruned, "deleted keys are not pruned again")
+
+	assert.Equal(t, []byte("db"), target.latest("/copy/db"))
+	assert.Equal(t, []byte("api"), target.latest("/copy/api"))
+	assert.Equal(t, []byte("other"), target.latest("/copy/other"))
+	assert.Nil(t, target.latest("/copy/removed"))
+	assert.NotNil(t, target.latest("/copy/nested/key"), "nested keys are only pruned for recursive queries")
+	assert.NotNil(t, target.latest("/copy-other"))
+	assert.NotNil(t, target.latest("/failed/removed"))
+
+	// Prune requires key prefix
+	_, err = storesync.Sync(context.Background(), source, target, []v1alpha1.SyncAction{
+		{
+			FromRef: &v1alpha1.SecretRef{Key: "/app/db"},
+			Prune:   ptr(true),
+		},
+	})
+	assert.Error(t, err)
+}
+
+// fakeStore is an in-memory store which keeps all written versions of a key.
+type fakeStore struct {
+	mu        sync.Mutex
+	versions  map[string][][]byte // nil version marks a soft-deleted key which is still listed
+	conflicts map[string]int      // number of writes to reject as concurrently modified
+	failures  map[string]int      // number of writes to fail with a temporary error
+	gets      int
+
+	delay       time.Duration                               // how long reads and writes take
+	onSet       func(ctx context.Context, key string) error // called before each write, can block it or fail it
+	inflight    atomic.Int32
+	maxInflight atomic.Int32
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{
+		versions:  map[string][][]byte{},
+		conflicts: map[string]int{},
+		failures:  map[string]int{},
+	}
+}
+
+func (s *fakeStore) GetSecret(ctx context.Context, key v1alpha1.SecretRef) ([]byte, error) {
+	defer s.track()()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gets++
+
+	versions := s.versions[key.Key]
+	index := len(versions)
+	if key.Version != nil {
+		index, _ = strconv.Atoi(*key.Version)
+	}
+	if index < 1 || index > len(versions) {
+		return nil, v1alpha1.ErrKeyNotFound
+	}
+	if versions[index-1] == nil {
+		return nil, v1alpha1.ErrKeyVersionDeleted
+	}
+
+	return versions[index-1], nil
+}
+
+func (s *fakeStore) ListSecretKeys(_ context.Context, query v1alpha1.SecretQuery) ([]v1alpha1.SecretRef, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queryPath := ""
+	if query.Path != nil {
+		queryPath = *query.Path
+	}
+
+	var result []v1alpha1.SecretRef
+	for key, versions := range s.versions {
+		if len(versions) == 0 || !strings.HasPrefix(key, queryPath) {
+			continue
+		}
+
+		relativeKey := strings.TrimPrefix(strings.TrimPrefix(key, queryPath), "/")
+		if query.IncludesDepth(strings.Count(relativeKey, "/")) {
+			result = append(result, v1alpha1.SecretRef{Key: key})
+		}
+	}
+	if len(result) == 0 {
+		return nil, v1alpha1.ErrKeyNotFound
+	}
+
+	return result, nil
+}
+
+func (s *fakeStore) SetSecret(ctx context.Context, key v1alpha1.SecretRef, value []byte) error {
+	defer s.track()()
+	if s.onSet != nil {
+		if err := s.onSet(ctx, key.Key); err != nil {
+			return err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conflicts[key.Key] > 0 {
+		s.conflicts[key.Key]--
+		return v1alpha1.ErrConflict
+	}
+
+	if s.failures[key.Key] > 0 {
+		s.failures[key.Key]--
+		return v1alpha1.ErrTransient
+	}
+
+	s.versions[key.Key] = append(s.versions[key.Key], value)
+	return nil
+}
+
+func (s *fakeStore) DeleteSecret(_ context.Context, key v1alpha1.SecretRef) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.latestLocked(key.Key) == nil {
+		return v1alpha1.ErrKeyNotFound
+	}
+
+	delete(s.versions, key.Key)
+	return nil
+}
+
+// track records the number of concurrent requests and simulates request delay.
+func (s *fakeStore) track() func() {
+	inflight := s.inflight.Add(1)
+	for {
+		current := s.maxInflight.Load()
+		if inflight <= current || s.maxInflight.CompareAndSwap(current, inflight) {
+			break
+		}
+	}
+	time.Sleep(s.delay)
+
+	return func() {
+		s.inflight.Add(-1)
+	}
+}
+
+func (s *fakeStore) latest(key string) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.latestLocked(key)
+}
+
+func (s *fakeStore) latestLocked(key string) []byte {
+	versions := s.versions[key]
+	if len(versions) == 0 {
+		return nil
+	}
+
+	return versions[len(versions)-1]
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestSyncOutcomes(t *testing.T) {
+	tests := []struct {
+		name         string
+		source       map[string]string
+		target       map[string]string
+		conflicts    map[string]int
+		failures     map[string]int
+		retryPolicy  *v1alpha1.RetryPolicy
+		wantErr      bool
+		wantOutcomes map[string]storesync.Outcome
+		wantWrites   map[string]int // number of versions written to target
+	}{
+		{
+			name:         "Synced",
+			source:       map[string]string{"/app/new": "new", "/app/changed": "changed"},
+			target:       map[string]string{"/app/changed": "previous"},
+			wantOutcomes: map[string]storesync.Outcome{"/app/new": storesync.OutcomeSynced, "/app/changed": storesync.OutcomeSynced},
+			wantWrites:   map[string]int{"/app/new": 1, "/app/changed": 1},
+		},
+		{
+			name:         "Unchanged",
+			source:       map[string]string{"/app/same": "same"},
+			target:       map[string]string{"/app/same": "same"},
+			wantOutcomes: map[string]storesync.Outcome{"/app/same": storesync.OutcomeUnchanged},
+			wantWrites:   map[string]int{"/app/same": 0},
+		},
+		{
+			name:         "Pruned",
+			source:       map[string]string{"/app/new": "new"},
+			target:       map[string]string{"/app/removed": "removed"},
+			wantOutcomes: map[string]storesync.Outcome{"/app/new": storesync.OutcomeSynced, "/app/removed": storesync.OutcomePruned},
+			wantWrites:   map[string]int{"/app/new": 1},
+		},
+		{
+			// Modified since read, reported without retrying
+			name:         "Conflicted",
+			source:       map[string]string{"/app/db": "secret"},
+			conflicts:    map[string]int{"/app/db": 1},
+			retryPolicy:  &v1alpha1.RetryPolicy{InitialBackoff: "1ms", MaxBackoff: "2ms"},
+			wantOutcomes: map[string]storesync.Outcome{"/app/db": storesync.OutcomeConflicted},
+			wantWrites:   map[string]int{"/app/db": 0},
+		},
+		{
+			name:         "Failed without retries",
+			source:       map[string]string{"/app/db": "secret"},
+			failures:     map[string]int{"/app/db": 1},
+			wantOutcomes: map[string]storesync.Outcome{"/app/db": storesync.OutcomeFailed},
+			wantWrites:   map[string]int{"/app/db": 0},
+		},
+		{
+			name:         "Retried until success",
+			source:       map[string]string{"/app/db": "secret"},
+			failures:     map[string]int{"/app/db": 2},
+			retryPolicy:  &v1alpha1.RetryPolicy{InitialBackoff: "1ms", MaxBackoff: "2ms"},
+			wantOutcomes: map[string]storesync.Outcome{"/app/db": storesync.OutcomeSynced},
+			wantWrites:   map[string]int{"/app/db": 1},
+		},
+		{
+			name:         "Retry attempts exhausted",
+			source:       map[string]string{"/app/db": "secret"},
+			failures:     map[string]int{"/app/db": 3},
+			retryPolicy:  &v1alpha1.RetryPolicy{InitialBackoff: "1ms", MaxBackoff: "2ms"},
+			wantOutcomes: map[string]storesync.Outcome{"/app/db": storesync.OutcomeFailed},
+			wantWrites:   map[string]int{"/app/db": 0},
+		},
+		{
+			name:        "Invalid retry policy",
+			source:      map[string]string{"/app/db": "secret"},
+			retryPolicy: &v1alpha1.RetryPolicy{InitialBackoff: "1s", MaxBackoff: "1ms"},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		ttp := tt
+		t.Run(ttp.name, func(t *testing.T) {
+			source, target := newFakeStore(), newFakeStore()
+			for key, value := range ttp.source {
+				source.versions[key] = [][]byte{[]byte(value)}
+			}
+			for key, value := range ttp.target {
+				target.versions[key] = [][]byte{[]byte(value)}
+			}
+			for key, count := range ttp.conflicts {
+				target.conflicts[key] = count
+			}
+			for key, count := range ttp.failures {
+				target.failures[key] = count
+			}
+
+			resp, err := storesync.Sync(context.Background(), source, target, []v1alpha1.SyncAction{
+				{
+					FromQuery: &v1alpha1.SecretQuery{Path: ptr("/app"), Key: v1alpha1.Query{Regexp: ".*"}},
+					Target:    v1alpha1.SyncTarget{KeyPrefix: ptr("/app/")},
+					Prune:     ptr(true),
+				},
+			}, storesync.WithRetryPolicy(ttp.retryPolicy))
+			if ttp.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			outcomes := make(map[string]storesync.Outcome)
+			for _, result := range resp.Results {
+				outcomes[result.Key] = result.Outcome
+				assert.Equal(t, result.Failed(), result.Error != "", result.Key)
+				if result.Outcome == storesync.OutcomeFailed {
+					assert.Contains(t, result.Error, v1alpha1.ErrTransient.Error())
+				}
+			}
+			assert.Equal(t, ttp.wantOutcomes, outcomes)
+
+			wantSuccess := true
+			for _, outcome := range ttp.wantOutcomes {
+				wantSuccess = wantSuccess && outcome != storesync.OutcomeFailed && outcome != storesync.OutcomeConflicted
+			}
+			assert.Equal(t, wantSuccess, resp.Success)
+
+			for key, writes := range ttp.wantWrites {
+				initial := 0
+				if _, ok := ttp.target[key]; ok {
+					initial = 1
+				}
+				assert.Len(t, target.versions[key], initial+writes, key)
+			}
+			assert.Zero(t, target.conflicts["/app/db"], "conflicted write is attempted once")
+		})
+	}
+}
+
+func TestSyncCollisions(t *testing.T) {
+	tests := []struct {
+		name         string
+		planPolicy   v1alpha1.ConflictPolicy
+		actionPolicy v1alpha1.ConflictPolicy
+		overrideData string
+		wantData     string
+		wantWinner   int
+		wantErr      bool
+	}{
+		{
+			name:    "Fail by default",
+			wantErr: true,
+		},
+		{
+			name:         "First wins",
+			actionPolicy: v1alpha1.ConflictPolicyFirstWins,
+			wantData:     `{"user":"app","pass":"app"}`,
+			wantWinner:   0,
+		},
+		{
+			name:         "Last wins by order",
+			actionPolicy: v1alpha1.ConflictPolicyLastWinsByOrder,
+			wantData:     `{"pass":"override"}`,
+			wantWinner:   1,
+		},
+		{
+			name:       "Plan policy",
+			planPolicy: v1alpha1.ConflictPolicyLastWinsByOrder,
+			wantData:   `{"pass":"override"}`,
+			wantWinner: 1,
+		},
+		{
+			name:         "Action policy overrides plan policy",
+			planPolicy:   v1alpha1.ConflictPolicyLastWinsByOrder,
+			actionPolicy: v1alpha1.ConflictPolicyFirstWins,
+			wantData:     `{"user":"app","pass":"app"}`,
+			wantWinner:   0,
+		},
+		{
+			name:         "Merge",
+			actionPolicy: v1alpha1.ConflictPolicyMerge,
+			wantData:     `{"user":"app","pass":"override"}`,
+			wantWinner:   -1,
+		},
+		{
+			name:         "Merge requires JSON maps",
+			actionPolicy: v1alpha1.ConflictPolicyMerge,
+			overrideData: "override",
+			wantErr:      true,
+		},
+		{
+			name:         "Unsupported policy",
+			actionPolicy: "random",
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		ttp := tt
+		t.Run(ttp.name, func(t *testing.T) {
+			overrideData := ttp.overrideData
+			if overrideData == "" {
+				overrideData = `{"pass":"override"}`
+			}
+
+			source := newFakeStore()
+			source.versions["/app/db"] = [][]byte{[]byte(`{"user":"app","pass":"app"}`)}
+			source.versions["/override/db"] = [][]byte{[]byte(overrideData)}
+			target := newFakeStore()
+
+			resp, err := storesync.Sync(context.Background(), source, target, []v1alpha1.SyncAction{
+				{
+					FromQuery: &v1alpha1.SecretQuery{Path: ptr("/app"), Key: v1alpha1.Query{Regexp: ".*"}},
+					Target:    v1alpha1.SyncTarget{KeyPrefix: ptr("/copy/")},
+				},
+				{
+					FromRef:        &v1alpha1.SecretRef{Key: "/override/db"},
+					Target:         v1alpha1.SyncTarget{Key: ptr("/copy/db")},
+					ConflictPolicy: ttp.actionPolicy,
+				},
+			}, storesync.WithConflictPolicy(ttp.planPolicy))
+			if ttp.wantErr {
+				assert.Error(t, err)
+				assert.Nil(t, target.latest("/copy/db"), "nothing should be synced")
+				return
+			}
+
+			require.NoError(t, err)
+			assert.True(t, resp.Success)
+			assert.JSONEq(t, ttp.wantData, string(target.latest("/copy/db")))
+
+			require.Len(t, resp.Collisions, 1)
+			assert.Equal(t, "/copy/db", resp.Collisions[0].Key)
+			assert.Equal(t, []int{0, 1}, resp.Collisions[0].Actions)
+			assert.Equal(t, ttp.wantWinner, resp.Collisions[0].Winner)
+		})
+	}
+}
+
+func TestSyncFailurePolicy(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      v1alpha1.FailurePolicy
+		actions     []v1alpha1.SyncAction
+		limits      *v1alpha1.StoreLimits
+		wantErr     error
+		wantStatus  bool
+		wantSynced  uint32
+		wantSkipped int
+	}{
+		{
+			name:   "Continue",
+			policy: v1alpha1.FailurePolicyContinue,
+			actions: []v1alpha1.SyncAction{
+				{FromRef: &v1alpha1.SecretRef{Key: "/app/db"}},
+				{FromRef: &v1alpha1.SecretRef{Key: "/app/missing"}},
+			},
+			wantStatus: true,
+			wantSynced: 1,
+		},
+		{
+			name:   "Fail at end",
+			policy: v1alpha1.FailurePolicyFailAtEnd,
+			actions: []v1alpha1.SyncAction{
+				{FromRef: &v1alpha1.SecretRef{Key: "/app/db"}},
+				{FromRef: &v1alpha1.SecretRef{Key: "/app/missing"}},
+			},
+			wantErr:    storesync.ErrSyncFailed,
+			wantStatus: true,
+			wantSynced: 1,
+		},
+		{
+			name:   "Fail fast on fetch",
+			policy: v1alpha1.FailurePolicyFailFast,
+			actions: []v1alpha1.SyncAction{
+				{FromRef: &v1alpha1.SecretRef{Key: "/app/db"}},
+				{FromRef: &v1alpha1.SecretRef{Key: "/app/missing"}},
+			},
+			wantErr: storesync.ErrSyncAborted,
+		},
+		{
+			name:   "Fail fast on write",
+			policy: v1alpha1.FailurePolicyFailFast,
+			actions: []v1alpha1.SyncAction{
+				{FromRef: &v1alpha1.SecretRef{Key: "/app/empty"}},
+			},
+			wantErr:    storesync.ErrSyncAborted,
+			wantStatus: true,
+		},
+		{
+			name:   "Fail fast skips later writes",
+			policy: v1alpha1.FailurePolicyFailFast,
+			actions: []v1alpha1.SyncAction{
+				{FromRef: &v1alpha1.SecretRef{Key: "/app/empty"}, Target: v1alpha1.SyncTarget{Key: ptr("/a")}},
+				{FromRef: &v1alpha1.SecretRef{Key: "/app/empty"}, Target: v1alpha1.SyncTarget{Key: ptr("/b")}},
+				{FromRef: &v1alpha1.SecretRef{Key: "/app/empty"}, Target: v1alpha1.SyncTarget{Key: ptr("/c")}},
+			},
+			limits:      &v1alpha1.StoreLimits{MaxConcurrency: 1},
+			wantErr:     storesync.ErrSyncAborted,
+			wantStatus:  true,
+			wantSkipped: 2,
+		},
+		{
+			name:   "Unsupported policy",
+			policy: "random",
+			actions: []v1alpha1.SyncAction{
+				{FromRef: &v1alpha1.SecretRef{Key: "/app/db"}},
+			},
+			wantErr: assert.AnError,
+		},
+	}
+
+	for _, tt := range tests {
+		ttp := tt
+		t.Run(ttp.name, func(t *testing.T) {
+			source := newFakeStore()
+			source.versions["/app/db"] = [][]byte{[]byte("db")}
+			source.versions["/app/empty"] = [][]byte{{}}
+			target := newFakeStore()
+
+			resp, err := storesync.Sync(context.Background(), source, target, ttp.actions,
+				storesync.WithFailurePolicy(ttp.policy),
+				storesync.WithTargetLimits(ttp.limits),
+			)
+			switch ttp.wantErr {
+			case nil:
+				assert.NoError(t, err)
+			case assert.AnError:
+				assert.Error(t, err)
+			default:
+				assert.ErrorIs(t, err, ttp.wantErr)
+			}
+
+			if !ttp.wantStatus {
+				assert.Nil(t, resp)
+				return
+			}
+			require.NotNil(t, resp)
+			assert.False(t, resp.Success)
+			assert.Equal(t, ttp.wantSynced, resp.Synced)
+
+			// Writes after the first failure are not started
+			var skipped int
+			for _, result := range resp.Results {
+				if result.Outcome == storesync.OutcomeSkipped {
+					assert.Equal(t, storesync.ErrSyncAborted.Error(), result.Error)
+					skipped++
+				}
+			}
+			assert.Equal(t, ttp.wantSkipped, skipped)
+			assert.Empty(t, resp.Unprocessed)
+		})
+	}
+}
+
+func TestSyncResults(t *testing.T) {
+	source := newFakeStore()
+	source.versions["/app/new"] = [][]byte{[]byte("new")}
+	source.versions["/app/conflicted"] = [][]byte{[]byte("conflicted")}
+	target := newFakeStore()
+	target.conflicts["/app/conflicted"] = 1
+
+	resp, err := storesync.Sync(context.Background(), source, target, []v1alpha1.SyncAction{
+		{
+			FromQuery: &v1alpha1.SecretQuery{Path: ptr("/app"), Key: v1alpha1.Query{Regexp: ".*"}},
+		},
+	})
+	require.NoError(t, err)
+	assert.False(t, resp.Success)
+
+	// Results are sorted by key and identify their sources without exposing values
+	require.Len(t, resp.Results, 2)
+	result := resp.Results[1]
+	assert.Equal(t, "/app/new", result.Key)
+	assert.Equal(t, []v1alpha1.SecretRef{{Key: "/app/new"}}, result.SourceRefs)
+	assert.Len(t, result.ValueHash, 64)
+	assert.NotEqual(t, "11507a0e2f5e69d5dfa40a62a1bd7b6ee57e6bcd85c67c9b8431b36fff21c437", result.ValueHash, "hash is keyed")
+	assert.False(t, result.Failed())
+	assert.True(t, resp.Results[0].Failed())
+	assert.NotEmpty(t, resp.Results[0].Error)
+
+	// Hashes are keyed per sync, so they cannot be compared across syncs
+	resp, err = storesync.Sync(context.Background(), source, newFakeStore(), []v1alpha1.SyncAction{
+		{FromRef: &v1alpha1.SecretRef{Key: "/app/new"}},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Results, 1)
+	assert.NotEqual(t, result.ValueHash, resp.Results[0].ValueHash)
+
+	// Digests of audited values can be compared across syncs
+	assert.Equal(t, "11507a0e2f5e69d5dfa40a62a1bd7b6ee57e6bcd85c67c9b8431b36fff21c437", result.ValueDigest)
+	assert.Equal(t, result.ValueDigest, resp.Results[0].ValueDigest)
+}
+
+func TestSyncLimits(t *testing.T) {
+	source := newFakeStore()
+	source.delay = 5 * time.Millisecond
+	for i := range 20 {
+		source.versions[fmt.Sprintf("/app/key-%d", i)] = [][]byte{[]byte("value")}
+	}
+	target := newFakeStore()
+	target.delay = 5 * time.Millisecond
+
+	actions := []v1alpha1.SyncAction{
+		{
+			FromQuery: &v1alpha1.SecretQuery{Path: ptr("/app"), Key: v1alpha1.Query{Regexp: ".*"}},
+			Target:    v1alpha1.SyncTarget{KeyPrefix: ptr("/copy/")},
+		},
+	}
+
+	resp, err := storesync.Sync(context.Background(), source, target, actions,
+		storesync.WithSourceLimits(&v1alpha1.StoreLimits{MaxConcurrency: 3}),
+		storesync.WithTargetLimits(&v1alpha1.StoreLimits{MaxConcurrency: 2}),
+	)
+	require.NoError(t, err)
+	assert.True(t, resp.Success)
+	assert.LessOrEqual(t, source.maxInflight.Load(), int32(3))
+	assert.LessOrEqual(t, target.maxInflight.Load(), int32(2))
+
+	// Requests per second
+	target = newFakeStore()
+	start := time.Now()
+	resp, err = storesync.Sync(context.Background(), source, target, actions[:1],
+		storesync.WithTargetLimits(&v1alpha1.StoreLimits{RequestsPerSecond: 1000}),
+	)
+	require.NoError(t, err)
+	assert.True(t, resp.Success)
+	assert.GreaterOrEqual(t, time.Since(start), 39*time.Millisecond, "40 reads and writes should take at least 39ms")
+
+	// Invalid limits
+	_, err = storesync.Sync(context.Background(), source, target, actions,
+		storesync.WithSourceLimits(&v1alpha1.StoreLimits{MaxConcurrency: -1}),
+	)
+	assert.Error(t, err)
+}
+
+func TestSyncCancel(t *testing.T) {
+	source := newFakeStore()
+	for i := range 5 {
+		source.versions[fmt.Sprintf("/app/key-%d", i)] = [][]byte{[]byte("value")}
+	}
+	actions := []v1alpha1.SyncAction{
+		{
+			FromQuery: &v1alpha1.SecretQuery{Path: ptr("/app"), Key: v1alpha1.Query{Regexp: ".*"}},
+			Target:    v1alpha1.SyncTarget{KeyPrefix: ptr("/copy/")},
+		},
+	}
+
+	// Nothing is written once cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	target := newFakeStore()
+	resp, err := storesync.Sync(ctx, source, target, actions)
+	assert.ErrorIs(t, err, storesync.ErrSyncAborted)
+	assert.ErrorIs(t, err, context.Canceled)
+	require.NotNil(t, resp)
+	assert.False(t, resp.Success)
+	assert.Equal(t, []int{0}, resp.Failed)
+	assert.Empty(t, target.versions)
+
+	// In-flight writes finish, remaining keys are reported as unprocessed
+	started, release := make(chan string), make(chan struct{})
+	target = newFakeStore()
+	target.onSet = func(context.Context, string) error {
+		started <- ""
+		<-release
+		return nil
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-started
+		cancel()
+		close(release)
+	}()
+	resp, err = storesync.Sync(ctx, source, target, actions,
+		storesync.WithTargetLimits(&v1alpha1.StoreLimits{MaxConcurrency: 1}),
+	)
+	assert.ErrorIs(t, err, context.Canceled)
+	require.NotNil(t, resp)
+	assert.False(t, resp.Success)
+	assert.Equal(t, uint32(1), resp.Synced)
+	assert.Len(t, resp.Unprocessed, 4)
+	for _, key := range resp.Unprocessed {
+		assert.Nil(t, target.latest(key))
+	}
+
+	// Writes still running after the grace period are cancelled
+	target = newFakeStore()
+	target.onSet = func(ctx context.Context, key string) error {
+		started <- key
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-started
+		cancel()
+	}()
+	resp, err = storesync.Sync(ctx, source, target, actions,
+		storesync.WithTargetLimits(&v1alpha1.StoreLimits{MaxConcurrency: 1}),
+		storesync.WithWriteGracePeriod(time.Millisecond),
+	)
+	assert.ErrorIs(t, err, context.Canceled)
+	require.NotNil(t, resp)
+	assert.Zero(t, resp.Synced)
+	assert.Len(t, resp.Unprocessed, 5)
+	assert.Empty(t, target.versions)
+
+	// Writes waiting to be retried stop once cancelled
+	target = newFakeStore()
+	target.onSet = func(_ context.Context, key string) error {
+		started <- key
+		return v1alpha1.ErrTransient
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-started
+		cancel()
+	}()
+	resp, err = storesync.Sync(ctx, source, target, actions,
+		storesync.WithTargetLimits(&v1alpha1.StoreLimits{MaxConcurrency: 1}),
+		storesync.WithRetryPolicy(&v1alpha1.RetryPolicy{MaxAttempts: 2, InitialBackoff: "1h", MaxBackoff: "1h"}),
+	)
+	assert.ErrorIs(t, err, context.Canceled)
+	require.NotNil(t, resp)
+	assert.Zero(t, resp.Synced)
+	assert.Len(t, resp.Unprocessed, 4)
+}
+
+func TestSyncActionTimeout(t *testing.T) {
+	source := newFakeStore()
+	source.delay = 50 * time.Millisecond
+	source.versions["/a"] = [][]byte{[]byte("a")}
+
+	// Fetch exceeding action timeout fails the action
+	resp, err := storesync.Sync(context.Background(), source, newFakeStore(), []v1alpha1.SyncAction{
+		{FromRef: &v1alpha1.SecretRef{Key: "/a"}, Timeout: "10ms"},
+	})
+	require.NoError(t, err)
+	assert.False(t, resp.Success)
+	assert.Equal(t, []int{0}, resp.Failed)
+
+	// Invalid timeout
+	_, err = storesync.Sync(context.Background(), source, newFakeStore(), []v1alpha1.SyncAction{
+		{FromRef: &v1alpha1.SecretRef{Key: "/a"}, Timeout: "soon"},
+	})
+	assert.Error(t, err)
+}
+
+func TestSyncTargets(t *testing.T) {
+	source := newFakeStore()
+	source.versions["/a"] = [][]byte{[]byte("a")}
+	source.versions["/b"] = [][]byte{[]byte("b")}
+	actions := []v1alpha1.SyncAction{
+		{FromRef: &v1alpha1.SecretRef{Key: "/a"}},
+		{FromRef: &v1alpha1.SecretRef{Key: "/b"}},
+	}
+
+	eu, us, apac := newFakeStore(), newFakeStore(), newFakeStore()
+	apac.failures["/a"] = 1
+	resp, err := storesync.SyncTargets(context.Background(), source, []storesync.Target{
+		{Name: "eu", Store: eu},
+		{Name: "us", Store: us, Actions: []int{1}},
+		{Name: "apac", Store: apac},
+	}, actions)
+	require.NoError(t, err)
+
+	// Values are fetched once for all targets
+	assert.Equal(t, 2, source.gets)
+
+	// Each target only receives its actions
+	assert.Equal(t, []byte("a"), eu.latest("/a"))
+	assert.Equal(t, []byte("b"), eu.latest("/b"))
+	assert.Nil(t, us.latest("/a"))
+	assert.Equal(t, []byte("b"), us.latest("/b"))
+
+	// Success is accounted per target
+	require.Len(t, resp.Targets, 3)
+	assert.Equal(t, "eu", resp.Targets[0].Name)
+	assert.True(t, resp.Targets[0].Success)
+	assert.Equal(t, uint32(1), resp.Targets[1].Total)
+	assert.True(t, resp.Targets[1].Success)
+	assert.False(t, resp.Targets[2].Success)
+	assert.Equal(t, uint32(1), resp.Targets[2].Synced)
+
+	assert.False(t, resp.Success)
+	assert.Equal(t, uint32(5), resp.Total)
+	assert.Equal(t, uint32(4), resp.Synced)
+	assert.Contains(t, resp.Status, "failed targets: apac")
+	require.Len(t, resp.Results, 5)
+	assert.Equal(t, "apac", resp.Results[0].Target)
+	assert.Equal(t, storesync.OutcomeFailed, resp.Results[0].Outcome)
+
+	// Invalid targets
+	invalidTargets := [][]storesync.Target{
+		{},
+		{{Name: "eu", Store: eu}, {Name: "eu", Store: us}},
+		{{Name: "eu", Store: eu}, {Store: us}},
+		{{Name: "eu", Store: eu, Actions: []int{2}}},
+	}
+	for _, targets := range invalidTargets {
+		_, err = storesync.SyncTargets(context.Background(), source, targets, actions)
+		assert.Error(t, err, targets)
+	}
+}
+
+func TestPlan(t *testing.T) {
+	source := newFakeStore()
+	source.versions["/app/new"] = [][]byte{[]byte("new")}
+	source.versions["/app/changed"] = [][]byte{[]byte("changed")}
+	source.versions["/app/same"] = [][]byte{[]byte("same")}
+	target := newFakeStore()
+	target.versions["/copy/changed"] = [][]byte{[]byte("previous")}
+	target.versions["/copy/same"] = [][]byte{[]byte("same")}
+	target.versions["/copy/removed"] = [][]byte{[]byte("removed")}
+	target.versions["/copy/deleted"] = [][]byte{[]byte("deleted"), nil}
+
+	plan, err := storesync.Plan(context.Background(), source, target, []v1alpha1.SyncAction{
+		{
+			FromQuery: &v1alpha1.SecretQuery{Path: ptr("/app"), Key: v1alpha1.Query{Regexp: ".*"}},
+			Target:    v1alpha1.SyncTarget{KeyPrefix: ptr("/copy/")},
+			Prune:     ptr(true),
+		},
+	})
+	require.NoError(t, err)
+
+	actions := make(map[string]storesync.PlanAction)
+	for _, key := range plan.Keys {
+		actions[key.Key] = key.Action
+	}
+	assert.Equal(t, map[string]storesync.PlanAction{
+		"/copy/new":     storesync.PlanActionCreate,
+		"/copy/changed": storesync.PlanActionUpdate,
+		"/copy/same":    storesync.PlanActionUnchanged,
+		"/copy/removed": storesync.PlanActionDelete,
+	}, actions)
+	assert.Equal(t, uint32(1), plan.Create)
+	assert.Equal(t, uint32(1), plan.Update)
+	assert.Equal(t, uint32(1), plan.Unchanged)
+	assert.Equal(t, uint32(1), plan.Delete)
+
+	// Values are only exposed as hashes and lengths
+	for _, key := range plan.Keys {
+		if key.Key != "/copy/same" {
+			continue
+		}
+		assert.Equal(t, key.Current, key.Desired)
+		assert.Equal(t, 4, key.Desired.Length)
+		assert.NotContains(t, key.Desired.Hash, "same")
+	}
+
+	// Target is not modified
+	assert.Equal(t, []byte("previous"), target.latest("/copy/changed"))
+	assert.Equal(t, []byte("removed"), target.latest("/copy/removed"))
+	assert.Nil(t, target.latest("/copy/new"))
+}
+
+func TestPlanTargets(t *testing.T) {
+	source := newFakeStore()
+	source.versions["/a"] = [][]byte{[]byte("a")}
+	current, empty := newFakeStore(), newFakeStore()
+	current.versions["/a"] = [][]byte{[]byte("a")}
+
+	plan, err := storesync.PlanTargets(context.Background(), source, []storesync.Target{
+		{Name: "current", Store: current},
+		{Name: "empty", Store: empty},
+	}, []v1alpha1.SyncAction{
+		{FromRef: &v1alpha1.SecretRef{Key: "/a"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, source.gets)
+
+	require.Len(t, plan.Targets, 2)
+	assert.Equal(t, uint32(1), plan.Targets[0].Unchanged)
+	assert.Equal(t, uint32(1), plan.Targets[1].Create)
+	assert.Equal(t, uint32(1), plan.Unchanged)
+	assert.Equal(t, uint32(1), plan.Create)
+
+	// Hashes can be compared across targets
+	require.Len(t, plan.Keys, 2)
+	assert.Equal(t, plan.Keys[0].Desired, plan.Keys[1].Desired)
+}
+
 //func BenchmarkSync(b *testing.B) {
 //	b.ReportAllocs()
 //
@@ -0,0 +1,158 @@
+// Copyright © 2024 Bank-Vaults Maintainers
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storesync
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bank-vaults/secret-sync/pkg/apis/v1alpha1"
+)
+
+// Target defines a target store for SyncTargets and PlanTargets.
+type Target struct {
+	Name    string // identifies the target in status, can be empty if there is only one target
+	Store   v1alpha1.StoreWriter
+	Actions []int // ids of actions synced to the target, all actions if empty
+}
+
+// TargetStatus defines Status of syncing to a single target.
+type TargetStatus struct {
+	Name string `json:"name"`
+	Status
+}
+
+// validateTargets checks if targets are valid for given actions.
+// Returns sorted ids of actions synced to each target.
+func validateTargets(targets []Target, actions []v1alpha1.SyncAction) ([][]int, error) {
+	targetActions := make([][]int, len(targets))
+	names := make(map[string]struct{}, len(targets))
+	for i, target := range targets {
+		if len(targets) > 1 {
+			if target.Name == "" {
+				return nil, fmt.Errorf("target %d has no name", i)
+			}
+			if _, exists := names[target.Name]; exists {
+				return nil, fmt.Errorf("duplicate target %q", target.Name)
+			}
+			names[target.Name] = struct{}{}
+		}
+
+		ids, err := validateTarget(target, actions)
+		if err != nil {
+			if target.Name != "" {
+				return nil, fmt.Errorf("invalid target %q: %w", target.Name, err)
+			}
+			return nil, err
+		}
+		targetActions[i] = ids
+	}
+
+	return targetActions, nil
+}
+
+// validateTarget checks if target is valid for given actions.
+// Returns sorted ids of actions synced to the target.
+func validateTarget(target Target, actions []v1alpha1.SyncAction) ([]int, error) {
+	if target.Store == nil {
+		return nil, errors.New("target is nil")
+	}
+
+	var ids []int
+	if len(target.Actions) == 0 {
+		for id := range actions {
+			ids = append(ids, id)
+		}
+	} else {
+		for _, id := range target.Actions {
+			if id < 0 || id >= len(actions) {
+				return nil, fmt.Errorf("unknown sync action %d", id)
+			}
+		}
+		ids = slices.Compact(slices.Sorted(slices.Values(target.Actions)))
+	}
+
+	for _, id := range ids {
+		if isPruneAction(actions[id]) {
+			if err := validatePruneAction(actions[id], target.Store); err != nil {
+				return nil, fmt.Errorf("invalid sync action %d: %w", id, err)
+			}
+		}
+	}
+
+	return ids, nil
+}
+
+// combineStatuses returns Status which sums up all target statuses.
+func combineStatuses(targets []TargetStatus, failedActions []int) *Status {
+	if len(targets) == 1 {
+		status := targets[0].Status
+		status.Targets = targets
+		return &status
+	}
+
+	status := &Status{
+		Failed:   failedActions,
+		Targets:  targets,
+		Success:  true,
+		SyncedAt: time.Now(),
+	}
+
+	var failedTargets []string
+	for _, target := range targets {
+		status.Total += target.Total
+		status.Synced += target.Synced
+		status.Unchanged += target.Unchanged
+		status.Pruned += target.Pruned
+		status.Unprocessed = append(status.Unprocessed, target.Unprocessed...)
+		status.Conflicted = append(status.Conflicted, target.Conflicted...)
+		status.Results = append(status.Results, target.Results...)
+		for _, collision := range target.Collisions {
+			if !slices.ContainsFunc(status.Collisions, collision.equal) {
+				status.Collisions = append(status.Collisions, collision)
+			}
+		}
+
+		if !target.Success {
+			status.Success = false
+			failedTargets = append(failedTargets, target.Name)
+		}
+	}
+
+	slices.Sort(status.Unprocessed)
+	status.Unprocessed = slices.Compact(status.Unprocessed)
+	slices.Sort(status.Conflicted)
+	status.Conflicted = slices.Compact(status.Conflicted)
+	slices.SortStableFunc(status.Collisions, func(a, b Collision) int {
+		return strings.Compare(a.Key, b.Key)
+	})
+	sortResults(status.Results)
+
+	status.Status = fmt.Sprintf("Synced %d out of total %d keys to %d targets", status.Synced, status.Total, len(targets))
+	if status.Unchanged > 0 {
+		status.Status += fmt.Sprintf(", %d keys unchanged", status.Unchanged)
+	}
+	if status.Pruned > 0 {
+		status.Status += fmt.Sprintf(", pruned %d keys", status.Pruned)
+	}
+	if len(failedTargets) > 0 {
+		status.Status += fmt.Sprintf(", failed targets: %s", strings.Join(failedTargets, ", "))
+	}
+
+	return status
+}